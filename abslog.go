@@ -22,29 +22,59 @@ type ContextKeyType string
 
 var contextKey ContextKeyType = ContextKeyType(defaultContextKey)
 
+// globalLogger is the logger currently backing the global logging functions
+var globalLogger AbsLog
+
 // contextSeparator is the current string used to separate context values from log messages
 var contextSeparator = defaultContextSeparator
 
 // AbsLog defines the interface for abstracted logging functionality.
-// It provides methods for logging at different levels with optional formatting.
+// It provides methods for logging at different levels with optional formatting
+// and context values.
 type AbsLog interface {
+	Trace(args ...any)
+	Tracef(format string, args ...any)
+	TraceCtx(ctx context.Context, args ...any)
+	TraceCtxf(ctx context.Context, format string, args ...any)
+
 	Debug(args ...any)
 	Debugf(format string, args ...any)
+	DebugCtx(ctx context.Context, args ...any)
+	DebugCtxf(ctx context.Context, format string, args ...any)
 
 	Info(args ...any)
 	Infof(format string, args ...any)
+	InfoCtx(ctx context.Context, args ...any)
+	InfoCtxf(ctx context.Context, format string, args ...any)
 
 	Warn(args ...any)
 	Warnf(format string, args ...any)
+	WarnCtx(ctx context.Context, args ...any)
+	WarnCtxf(ctx context.Context, format string, args ...any)
 
 	Error(args ...any)
 	Errorf(format string, args ...any)
+	ErrorCtx(ctx context.Context, args ...any)
+	ErrorCtxf(ctx context.Context, format string, args ...any)
+	ErrorErr(err error, msg string, kv ...any)
 
 	Fatal(args ...any)
 	Fatalf(format string, args ...any)
+	FatalCtx(ctx context.Context, args ...any)
+	FatalCtxf(ctx context.Context, format string, args ...any)
 
 	Panic(args ...any)
 	Panicf(format string, args ...any)
+	PanicCtx(ctx context.Context, args ...any)
+	PanicCtxf(ctx context.Context, format string, args ...any)
+
+	Log(level LogLevel, args ...any)
+	Logf(level LogLevel, format string, args ...any)
+	LogCtx(ctx context.Context, level LogLevel, args ...any)
+	LogCtxf(ctx context.Context, level LogLevel, format string, args ...any)
+
+	With(kv ...any) AbsLog
+	Named(name string) AbsLog
 }
 
 func init() {
@@ -95,6 +125,12 @@ func SetCtxSeparator(separator string) {
 	}
 }
 
+// Trace logs a message at level Trace on the standard logger.
+var Trace func(args ...any)
+var TraceCtx func(ctx context.Context, args ...any)
+var Tracef func(format string, args ...any)
+var TraceCtxf func(ctx context.Context, format string, args ...any)
+
 // Debug logs a message at level Debug on the standard logger.
 var Debug func(args ...any)
 var DebugCtx func(ctx context.Context, args ...any)
@@ -119,6 +155,10 @@ var ErrorCtx func(ctx context.Context, args ...any)
 var Errorf func(format string, args ...any)
 var ErrorCtxf func(ctx context.Context, format string, args ...any)
 
+// ErrorErr logs a message at level Error on the standard logger with err and
+// the given key/value pairs attached as structured fields.
+var ErrorErr func(err error, msg string, kv ...any)
+
 // Fatal logs a message at level Fatal on the standard logger.
 var Fatal func(args ...any)
 var FatalCtx func(ctx context.Context, args ...any)
@@ -131,6 +171,12 @@ var PanicCtx func(ctx context.Context, args ...any)
 var Panicf func(format string, args ...any)
 var PanicCtxf func(ctx context.Context, format string, args ...any)
 
+// Log logs a message at the given built-in or custom level on the standard logger.
+var Log func(level LogLevel, args ...any)
+var LogCtx func(ctx context.Context, level LogLevel, args ...any)
+var Logf func(level LogLevel, format string, args ...any)
+var LogCtxf func(ctx context.Context, level LogLevel, format string, args ...any)
+
 // SetLoggerType configures the global logger to use the specified logger type
 // (ZapLogger or LogrusLogger) with default settings.
 func SetLoggerType(typ LoggerType) {
@@ -149,114 +195,59 @@ func SetLoggerType(typ LoggerType) {
 // SetLogger sets the provided AbsLog instance as the global logger,
 // updating all global logging function variables.
 func SetLogger(logger AbsLog) {
+	globalLogger = logger
+
+	// Trace
+	Trace = logger.Trace
+	Tracef = logger.Tracef
+	TraceCtx = logger.TraceCtx
+	TraceCtxf = logger.TraceCtxf
+
 	// Debug
 	Debug = logger.Debug
 	Debugf = logger.Debugf
-	DebugCtx = logCtx(logger.Debug)
-	DebugCtxf = logCtxf(logger.Debugf)
+	DebugCtx = logger.DebugCtx
+	DebugCtxf = logger.DebugCtxf
 
 	// Info
 	Info = logger.Info
 	Infof = logger.Infof
-	InfoCtx = logCtx(logger.Info)
-	InfoCtxf = logCtxf(logger.Infof)
+	InfoCtx = logger.InfoCtx
+	InfoCtxf = logger.InfoCtxf
 
 	// Warn
 	Warn = logger.Warn
 	Warnf = logger.Warnf
-	WarnCtx = logCtx(logger.Warn)
-	WarnCtxf = logCtxf(logger.Warnf)
+	WarnCtx = logger.WarnCtx
+	WarnCtxf = logger.WarnCtxf
 
 	// Error
 	Error = logger.Error
 	Errorf = logger.Errorf
-	ErrorCtx = logCtx(logger.Error)
-	ErrorCtxf = logCtxf(logger.Errorf)
+	ErrorCtx = logger.ErrorCtx
+	ErrorCtxf = logger.ErrorCtxf
+	ErrorErr = logger.ErrorErr
 
 	// Fatal
 	Fatal = logger.Fatal
 	Fatalf = logger.Fatalf
-	FatalCtx = logCtx(logger.Fatal)
-	FatalCtxf = logCtxf(logger.Fatalf)
+	FatalCtx = logger.FatalCtx
+	FatalCtxf = logger.FatalCtxf
 
 	// Panic
 	Panic = logger.Panic
 	Panicf = logger.Panicf
-	PanicCtx = logCtx(logger.Panic)
-	PanicCtxf = logCtxf(logger.Panicf)
+	PanicCtx = logger.PanicCtx
+	PanicCtxf = logger.PanicCtxf
+
+	// Custom levels
+	Log = logger.Log
+	Logf = logger.Logf
+	LogCtx = logger.LogCtx
+	LogCtxf = logger.LogCtxf
 }
 
-// getCtxValues extracts and formats context values for logging.
-// It supports three formats:
-// - map[string]any: formatted as "key1=value1, key2=value2"
-// - []string: formatted as "item1, item2, item3"
-// - string: formatted as-is
-// Returns an empty string if context is nil or contains no values.
-func getCtxValues(ctx context.Context) string {
-	if ctx == nil || ctx.Value(contextKey) == nil {
-		return ""
-	}
-
-	// Type switch to handle different context value formats
-	switch ctxValues := ctx.Value(contextKey).(type) {
-	case map[string]any:
-		// Use strings.Builder for efficient string construction
-		var builder strings.Builder
-		builder.WriteString("[")
-		first := true
-		for k, v := range ctxValues {
-			if !first {
-				builder.WriteString(", ")
-			}
-			builder.WriteString(k)
-			builder.WriteString("=")
-			builder.WriteString(fmt.Sprint(v)) // Single conversion, not template
-			first = false
-		}
-		builder.WriteString("]")
-		builder.WriteString(contextSeparator)
-		return builder.String()
-	case []string:
-		// Direct concatenation without fmt.Sprintf
-		return "[" + strings.Join(ctxValues, ", ") + "]" + contextSeparator
-	case string:
-		// Direct concatenation without fmt.Sprintf
-		return "[" + ctxValues + "]" + contextSeparator
-	default:
-		// Unsupported type, return empty string
-		return ""
-	}
-}
-
-// logCtx wraps a regular log function to add context support.
-// It extracts context values and prepends them to the log message.
-func logCtx(log func(args ...any)) func(ctx context.Context, args ...any) {
-	return func(ctx context.Context, args ...any) {
-		// Extract formatted context values
-		ctxValues := getCtxValues(ctx)
-		if ctxValues == "" {
-			// No context values, log normally
-			log(args...)
-		} else {
-			message := fmt.Sprint(args...)
-			log(ctxValues + " " + message)
-		}
-	}
-}
-
-// logCtxf wraps a formatted log function to add context support.
-// It extracts context values and prepends them to the formatted log message.
-func logCtxf(log func(format string, args ...any)) func(ctx context.Context, format string, args ...any) {
-	return func(ctx context.Context, format string, args ...any) {
-		// Extract formatted context values
-		ctxValues := getCtxValues(ctx)
-		if ctxValues == "" {
-			// No context values, log normally
-			log(format, args...)
-		} else {
-			// Combine context with user format - single formatting operation
-			newFormat := ctxValues + " " + format
-			log(newFormat, args...)
-		}
-	}
+// GetLogger returns the logger currently backing the global logging functions.
+func GetLogger() AbsLog {
+	return globalLogger
 }
@@ -0,0 +1,111 @@
+package abslog
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCtxMethods(t *testing.T) {
+	ctx := WithValues(context.Background(), "request_id", "r1")
+
+	for _, backend := range testBackends {
+		logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+		tests := []struct {
+			name  string
+			call  func()
+			level LogLevel
+			msg   string
+		}{
+			{"TraceCtx", func() { logger.TraceCtx(ctx, "a", 1) }, TraceLevel, "a1"},
+			{"TraceCtxf", func() { logger.TraceCtxf(ctx, "a%d", 1) }, TraceLevel, "a1"},
+			{"DebugCtx", func() { logger.DebugCtx(ctx, "a", 1) }, DebugLevel, "a1"},
+			{"DebugCtxf", func() { logger.DebugCtxf(ctx, "a%d", 1) }, DebugLevel, "a1"},
+			{"InfoCtx", func() { logger.InfoCtx(ctx, "a", 1) }, InfoLevel, "a1"},
+			{"InfoCtxf", func() { logger.InfoCtxf(ctx, "a%d", 1) }, InfoLevel, "a1"},
+			{"WarnCtx", func() { logger.WarnCtx(ctx, "a", 1) }, WarnLevel, "a1"},
+			{"WarnCtxf", func() { logger.WarnCtxf(ctx, "a%d", 1) }, WarnLevel, "a1"},
+			{"ErrorCtx", func() { logger.ErrorCtx(ctx, "a", 1) }, ErrorLevel, "a1"},
+			{"ErrorCtxf", func() { logger.ErrorCtxf(ctx, "a%d", 1) }, ErrorLevel, "a1"},
+			{"LogCtx", func() { logger.LogCtx(ctx, WarnLevel, "a", 1) }, WarnLevel, "a1"},
+			{"LogCtxf", func() { logger.LogCtxf(ctx, WarnLevel, "a%d", 1) }, WarnLevel, "a1"},
+			{"PanicCtx", func() { logger.PanicCtx(ctx, "a", 1) }, PanicLevel, "a1"},
+			{"PanicCtxf", func() { logger.PanicCtxf(ctx, "a%d", 1) }, PanicLevel, "a1"},
+		}
+
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				func() {
+					defer func() {
+						if r := recover(); (r != nil) != (tt.level == PanicLevel) {
+							t.Errorf("recovered %v at level %v", r, tt.level)
+						}
+					}()
+					tt.call()
+				}()
+
+				entry, _ := out.last(t)
+				if entry.Level != tt.level || entry.Message != tt.msg {
+					t.Errorf("got %v %q, want %v %q", entry.Level, entry.Message, tt.level, tt.msg)
+				}
+				assertField(t, entry, "request_id", "r1")
+			})
+		}
+	}
+}
+
+func TestCtxMethodsWithoutValues(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, ConsoleEncoder)
+			logger.InfoCtx(context.Background(), "plain")
+			logger.InfoCtx(nil, "nil context")
+
+			entry, _ := out.last(t)
+			if entry.Message != "nil context" || len(entry.Fields) != 0 {
+				t.Errorf("got %q with fields %v, want the bare message", entry.Message, fieldKeys(entry))
+			}
+		})
+	}
+}
+
+func TestGlobalFunctions(t *testing.T) {
+	previous := GetLogger()
+	t.Cleanup(func() { SetLogger(previous) })
+
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+			SetLogger(logger)
+			if GetLogger() != logger {
+				t.Fatal("GetLogger did not return the logger set with SetLogger")
+			}
+
+			ctx := WithValues(context.Background(), "user", "ann")
+			Trace("trace")
+			Debugf("debug %d", 1)
+			InfoCtx(ctx, "info")
+			WarnCtxf(ctx, "warn %s", "x")
+			Errorf("error")
+			ErrorErr(&testErr{msg: "boom"}, "failed")
+			LogCtx(ctx, InfoLevel, "log")
+
+			want := []struct {
+				level LogLevel
+				msg   string
+			}{
+				{TraceLevel, "trace"}, {DebugLevel, "debug 1"}, {InfoLevel, "info"}, {WarnLevel, "warn x"},
+				{ErrorLevel, "error"}, {ErrorLevel, "failed"}, {InfoLevel, "log"},
+			}
+			if out.count() != len(want) {
+				t.Fatalf("got %d entries, want %d", out.count(), len(want))
+			}
+			for i, w := range want {
+				entry := out.entries[i]
+				if entry.Level != w.level || entry.Message != w.msg {
+					t.Errorf("entry %d: got %v %q, want %v %q", i, entry.Level, entry.Message, w.level, w.msg)
+				}
+			}
+			assertField(t, out.entries[2], "user", "ann")
+		})
+	}
+}
@@ -1,26 +1,70 @@
 package abslog
 
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// nameKey is the field key under which the logger name is recorded.
+const nameKey = "logger"
+
+// leveledLogger is the set of logging methods a logger must provide to be
+// wrapped by a LoggerAdapter.
+type leveledLogger interface {
+	Debug(args ...any)
+	Debugf(format string, args ...any)
+	Info(args ...any)
+	Infof(format string, args ...any)
+	Warn(args ...any)
+	Warnf(format string, args ...any)
+	Error(args ...any)
+	Errorf(format string, args ...any)
+	Fatal(args ...any)
+	Fatalf(format string, args ...any)
+	Panic(args ...any)
+	Panicf(format string, args ...any)
+}
+
+// fieldLogger is implemented by the built-in backends able to emit
+// structured fields natively. callerSkip is the number of frames to skip,
+// in addition to the usual ones, to find the call site reported as caller.
+type fieldLogger interface {
+	logFields(level LogLevel, msg string, fields []Field, callerSkip int)
+}
+
+// levelEnabler is implemented by the built-in backends to skip building
+// messages for disabled levels.
+type levelEnabler interface {
+	enabled(level LogLevel) bool
+}
+
 // LoggerAdapter adapts any logger that implements the basic logging methods
 // to the AbsLog interface. This provides a consistent abstraction layer
 // while handling type conversions.
 type LoggerAdapter struct {
-	logger interface {
-		Debug(args ...any)
-		Debugf(format string, args ...any)
-		Info(args ...any)
-		Infof(format string, args ...any)
-		Warn(args ...any)
-		Warnf(format string, args ...any)
-		Error(args ...any)
-		Errorf(format string, args ...any)
-		Fatal(args ...any)
-		Fatalf(format string, args ...any)
-		Panic(args ...any)
-		Panicf(format string, args ...any)
-	}
+	logger   leveledLogger
+	redactor *redactor
+	// ctxAsFields emits context values as structured fields instead of a message prefix
+	ctxAsFields bool
+	// ctxKey is the key used to retrieve context values; empty means the global key
+	ctxKey ContextKeyType
+	// ctxSeparator separates context values from the message; empty means the global separator
+	ctxSeparator string
+	// name is the dot-separated name given through Named
+	name string
+	// fields are the fields accumulated through With
+	fields []Field
+	// callerSkip is the number of extra frames between the caller of log and the reported call site
+	callerSkip int
+	// hooks are the hooks of this logger, run after the global ones
+	hooks []Hook
 }
 
 // NewLoggerAdapter creates a new LoggerAdapter wrapping the provided logger.
+// The logger methods are called two frames below the call site: loggers
+// reporting the caller must skip the AbsLog method and the adapter's log method.
 func NewLoggerAdapter(logger interface {
 	Debug(args ...any)
 	Debugf(format string, args ...any)
@@ -38,62 +82,307 @@ func NewLoggerAdapter(logger interface {
 	return &LoggerAdapter{logger: logger}
 }
 
+// Trace logs a message at trace level.
+func (a *LoggerAdapter) Trace(args ...any) {
+	a.log(nil, TraceLevel, "", args, nil)
+}
+
+// Tracef logs a formatted message at trace level.
+func (a *LoggerAdapter) Tracef(format string, args ...any) {
+	a.log(nil, TraceLevel, format, args, nil)
+}
+
 // Debug logs a message at debug level.
 func (a *LoggerAdapter) Debug(args ...any) {
-	a.logger.Debug(args...)
+	a.log(nil, DebugLevel, "", args, nil)
 }
 
 // Debugf logs a formatted message at debug level.
 func (a *LoggerAdapter) Debugf(format string, args ...any) {
-	a.logger.Debugf(format, args...)
+	a.log(nil, DebugLevel, format, args, nil)
 }
 
 // Info logs a message at info level.
 func (a *LoggerAdapter) Info(args ...any) {
-	a.logger.Info(args...)
+	a.log(nil, InfoLevel, "", args, nil)
 }
 
 // Infof logs a formatted message at info level.
 func (a *LoggerAdapter) Infof(format string, args ...any) {
-	a.logger.Infof(format, args...)
+	a.log(nil, InfoLevel, format, args, nil)
 }
 
 // Warn logs a message at warn level.
 func (a *LoggerAdapter) Warn(args ...any) {
-	a.logger.Warn(args...)
+	a.log(nil, WarnLevel, "", args, nil)
 }
 
 // Warnf logs a formatted message at warn level.
 func (a *LoggerAdapter) Warnf(format string, args ...any) {
-	a.logger.Warnf(format, args...)
+	a.log(nil, WarnLevel, format, args, nil)
 }
 
 // Error logs a message at error level.
 func (a *LoggerAdapter) Error(args ...any) {
-	a.logger.Error(args...)
+	a.log(nil, ErrorLevel, "", args, nil)
 }
 
 // Errorf logs a formatted message at error level.
 func (a *LoggerAdapter) Errorf(format string, args ...any) {
-	a.logger.Errorf(format, args...)
+	a.log(nil, ErrorLevel, format, args, nil)
+}
+
+// ErrorErr logs a message at error level with err and the given key/value
+// pairs attached as structured fields.
+func (a *LoggerAdapter) ErrorErr(err error, msg string, kv ...any) {
+	a.log(nil, ErrorLevel, "%s", []any{msg}, append(fieldsFromKV(kv), Err(err)))
 }
 
 // Fatal logs a message at fatal level and exits the program.
 func (a *LoggerAdapter) Fatal(args ...any) {
-	a.logger.Fatal(args...)
+	a.log(nil, FatalLevel, "", args, nil)
 }
 
 // Fatalf logs a formatted message at fatal level and exits the program.
 func (a *LoggerAdapter) Fatalf(format string, args ...any) {
-	a.logger.Fatalf(format, args...)
+	a.log(nil, FatalLevel, format, args, nil)
 }
 
 // Panic logs a message at panic level and panics.
 func (a *LoggerAdapter) Panic(args ...any) {
-	a.logger.Panic(args...)
+	a.log(nil, PanicLevel, "", args, nil)
 }
 
 // Panicf logs a formatted message at panic level and panics.
 func (a *LoggerAdapter) Panicf(format string, args ...any) {
-	a.logger.Panicf(format, args...)
+	a.log(nil, PanicLevel, format, args, nil)
+}
+
+// TraceCtx logs a message at trace level with the context values found in ctx.
+func (a *LoggerAdapter) TraceCtx(ctx context.Context, args ...any) {
+	a.log(ctx, TraceLevel, "", args, nil)
+}
+
+// TraceCtxf logs a formatted message at trace level with the context values found in ctx.
+func (a *LoggerAdapter) TraceCtxf(ctx context.Context, format string, args ...any) {
+	a.log(ctx, TraceLevel, format, args, nil)
+}
+
+// DebugCtx logs a message at debug level with the context values found in ctx.
+func (a *LoggerAdapter) DebugCtx(ctx context.Context, args ...any) {
+	a.log(ctx, DebugLevel, "", args, nil)
+}
+
+// DebugCtxf logs a formatted message at debug level with the context values found in ctx.
+func (a *LoggerAdapter) DebugCtxf(ctx context.Context, format string, args ...any) {
+	a.log(ctx, DebugLevel, format, args, nil)
+}
+
+// InfoCtx logs a message at info level with the context values found in ctx.
+func (a *LoggerAdapter) InfoCtx(ctx context.Context, args ...any) {
+	a.log(ctx, InfoLevel, "", args, nil)
+}
+
+// InfoCtxf logs a formatted message at info level with the context values found in ctx.
+func (a *LoggerAdapter) InfoCtxf(ctx context.Context, format string, args ...any) {
+	a.log(ctx, InfoLevel, format, args, nil)
+}
+
+// WarnCtx logs a message at warn level with the context values found in ctx.
+func (a *LoggerAdapter) WarnCtx(ctx context.Context, args ...any) {
+	a.log(ctx, WarnLevel, "", args, nil)
+}
+
+// WarnCtxf logs a formatted message at warn level with the context values found in ctx.
+func (a *LoggerAdapter) WarnCtxf(ctx context.Context, format string, args ...any) {
+	a.log(ctx, WarnLevel, format, args, nil)
+}
+
+// ErrorCtx logs a message at error level with the context values found in ctx.
+func (a *LoggerAdapter) ErrorCtx(ctx context.Context, args ...any) {
+	a.log(ctx, ErrorLevel, "", args, nil)
+}
+
+// ErrorCtxf logs a formatted message at error level with the context values found in ctx.
+func (a *LoggerAdapter) ErrorCtxf(ctx context.Context, format string, args ...any) {
+	a.log(ctx, ErrorLevel, format, args, nil)
+}
+
+// FatalCtx logs a message at fatal level with the context values found in ctx and exits the program.
+func (a *LoggerAdapter) FatalCtx(ctx context.Context, args ...any) {
+	a.log(ctx, FatalLevel, "", args, nil)
+}
+
+// FatalCtxf logs a formatted message at fatal level with the context values found in ctx and exits the program.
+func (a *LoggerAdapter) FatalCtxf(ctx context.Context, format string, args ...any) {
+	a.log(ctx, FatalLevel, format, args, nil)
+}
+
+// PanicCtx logs a message at panic level with the context values found in ctx and panics.
+func (a *LoggerAdapter) PanicCtx(ctx context.Context, args ...any) {
+	a.log(ctx, PanicLevel, "", args, nil)
+}
+
+// PanicCtxf logs a formatted message at panic level with the context values found in ctx and panics.
+func (a *LoggerAdapter) PanicCtxf(ctx context.Context, format string, args ...any) {
+	a.log(ctx, PanicLevel, format, args, nil)
+}
+
+// Log logs a message at the given built-in or custom level.
+func (a *LoggerAdapter) Log(level LogLevel, args ...any) {
+	a.log(nil, level, "", args, nil)
+}
+
+// Logf logs a formatted message at the given built-in or custom level.
+func (a *LoggerAdapter) Logf(level LogLevel, format string, args ...any) {
+	a.log(nil, level, format, args, nil)
+}
+
+// LogCtx logs a message at the given built-in or custom level with the context values found in ctx.
+func (a *LoggerAdapter) LogCtx(ctx context.Context, level LogLevel, args ...any) {
+	a.log(ctx, level, "", args, nil)
+}
+
+// LogCtxf logs a formatted message at the given built-in or custom level with the context values found in ctx.
+func (a *LoggerAdapter) LogCtxf(ctx context.Context, level LogLevel, format string, args ...any) {
+	a.log(ctx, level, format, args, nil)
+}
+
+// With returns a child logger that adds the given key/value pairs to every entry.
+// kv accepts alternating keys and values as well as Field values.
+func (a *LoggerAdapter) With(kv ...any) AbsLog {
+	child := *a
+	child.fields = append(slices.Clip(a.fields), fieldsFromKV(kv)...)
+	return &child
+}
+
+// Named returns a child logger with name appended to the logger name,
+// separated by a dot. The name is recorded under the "logger" field.
+func (a *LoggerAdapter) Named(name string) AbsLog {
+	child := *a
+	if a.name != "" {
+		child.name = a.name + "." + name
+	} else {
+		child.name = name
+	}
+	return &child
+}
+
+// log builds the message and sends it with its fields to the wrapped logger.
+// The message is built with fmt.Sprint when format is empty and fmt.Sprintf
+// otherwise. Context values found in ctx under the logger context key are either prepended to the message
+// or emitted as fields, depending on ctxAsFields. Name, accumulated fields and
+// context values come before the entry fields. Hooks then receive the entry
+// and may change or drop it, after which the entry is counted (see
+// SetMetricsRecorder), fields with redacted keys are masked, error-valued
+// fields are expanded and every value goes through the redactor. Loggers without structured output support receive the
+// fields appended to the message as "key=value" pairs.
+//
+// Every logging method calls log directly so that backends can rely on a fixed
+// number of frames between the caller and the backend.
+func (a *LoggerAdapter) log(ctx context.Context, level LogLevel, format string, args []any, fields []Field) {
+	level = normalizeLevel(level)
+
+	// Fatal and panic entries always reach the backend, which owns their exit/panic behavior
+	le, canCheck := a.logger.(levelEnabler)
+	if canCheck && level < PanicLevel && !le.enabled(level) {
+		return
+	}
+
+	var msg string
+	if format == "" {
+		msg = fmt.Sprint(args...)
+	} else {
+		msg = fmt.Sprintf(format, args...)
+	}
+
+	ctxValues := ctxFields(ctx, a.currentCtxKey())
+	var ctxPrefix []Field
+	if len(ctxValues) > 0 && !a.ctxAsFields {
+		ctxPrefix, ctxValues = ctxValues, nil
+	}
+
+	if a.name != "" || len(a.fields) > 0 || len(ctxValues) > 0 {
+		all := make([]Field, 0, len(a.fields)+len(ctxValues)+len(fields)+2)
+		if a.name != "" {
+			all = append(all, Field{Key: nameKey, Value: a.name})
+		}
+		all = append(all, a.fields...)
+		all = append(all, keyedCtxFields(ctxValues)...)
+		fields = append(all, fields...)
+	}
+
+	if hooks := a.activeHooks(); len(hooks) > 0 {
+		entry := &Entry{
+			Level:   level,
+			Time:    time.Now(),
+			Message: msg,
+			Fields:  fields,
+			Context: ctx,
+			Logger:  a.name,
+			// Skip log and the adapter method
+			Caller: callerFrame(2 + a.callerSkip),
+		}
+		if !runHooks(hooks, entry) {
+			return
+		}
+		entry.Level = normalizeLevel(entry.Level)
+		if entry.Level != level && canCheck && entry.Level < PanicLevel && !le.enabled(entry.Level) {
+			return
+		}
+		level, msg, fields = entry.Level, entry.Message, entry.Fields
+	}
+
+	recordEntry(level, a.name)
+
+	msg = a.redactor.message(msg)
+	if len(ctxPrefix) > 0 {
+		msg = renderCtxFields(a.redactor.fields(ctxPrefix), a.currentCtxSeparator()) + " " + msg
+	}
+	fields = a.redactor.fields(expandFields(a.redactor.keyed(fields)))
+
+	if fl, ok := a.logger.(fieldLogger); ok {
+		fl.logFields(level, msg, fields, a.callerSkip)
+		return
+	}
+	if len(fields) > 0 {
+		msg += " " + formatFields(fields)
+	}
+	a.levelFunc(level)(msg)
+}
+
+// currentCtxKey returns the key used by this logger to retrieve context values.
+func (a *LoggerAdapter) currentCtxKey() ContextKeyType {
+	if a.ctxKey != "" {
+		return a.ctxKey
+	}
+	return contextKey
+}
+
+// currentCtxSeparator returns the separator used by this logger between context values and messages.
+func (a *LoggerAdapter) currentCtxSeparator() string {
+	if a.ctxSeparator != "" {
+		return a.ctxSeparator
+	}
+	return contextSeparator
+}
+
+// levelFunc returns the wrapped logger method for the given level.
+// Trace entries use the debug method and custom levels use the method of
+// the closest built-in level below them.
+func (a *LoggerAdapter) levelFunc(level LogLevel) func(args ...any) {
+	switch baseLevel(level) {
+	case TraceLevel, DebugLevel:
+		return a.logger.Debug
+	case WarnLevel:
+		return a.logger.Warn
+	case ErrorLevel:
+		return a.logger.Error
+	case PanicLevel:
+		return a.logger.Panic
+	case FatalLevel:
+		return a.logger.Fatal
+	default:
+		return a.logger.Info
+	}
 }
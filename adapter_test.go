@@ -0,0 +1,153 @@
+package abslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithAndNamed(t *testing.T) {
+	tests := []struct {
+		name       string
+		child      func(AbsLog) AbsLog
+		wantLogger string
+		wantFields map[string]string
+		// absent are fields that must not be logged
+		absent []string
+	}{
+		{
+			name:       "with",
+			child:      func(l AbsLog) AbsLog { return l.With("service", "api", Any("version", 3)) },
+			wantFields: map[string]string{"service": "api", "version": "3"},
+			absent:     []string{nameKey},
+		},
+		{
+			name:       "named",
+			child:      func(l AbsLog) AbsLog { return l.Named("http").Named("server") },
+			wantLogger: "http.server",
+			wantFields: map[string]string{nameKey: "http.server"},
+		},
+		{
+			name:       "named with",
+			child:      func(l AbsLog) AbsLog { return l.Named("db").With("table", "users").With("op", "select") },
+			wantLogger: "db",
+			wantFields: map[string]string{nameKey: "db", "table": "users", "op": "select"},
+		},
+		{
+			name:       "siblings",
+			child:      func(l AbsLog) AbsLog { parent := l.With("a", 1); parent.With("b", 2); return parent.With("c", 3) },
+			wantFields: map[string]string{"a": "1", "c": "3"},
+			absent:     []string{"b"},
+		},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+				tt.child(logger).Info("hello", "kv", "x")
+
+				entry, _ := out.last(t)
+				if entry.Logger != tt.wantLogger {
+					t.Errorf("logger = %q, want %q", entry.Logger, tt.wantLogger)
+				}
+				for key, want := range tt.wantFields {
+					assertField(t, entry, key, want)
+				}
+				for _, key := range tt.absent {
+					if _, ok := fieldText(entry, key); ok {
+						t.Errorf("unexpected field %q", key)
+					}
+				}
+
+				// The parent logger is not changed by its children
+				logger.Info("parent")
+				entry, _ = out.last(t)
+				if len(entry.Fields) != 0 {
+					t.Errorf("parent logged fields %v", fieldKeys(entry))
+				}
+			})
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	global := GetLogger()
+	logger, _ := newCaptureLogger(ZapLogger, JSONEncoder)
+
+	if got := FromContext(context.Background()); got != global {
+		t.Error("FromContext without logger did not return the global logger")
+	}
+	if got := FromContext(nil); got != global {
+		t.Error("FromContext(nil) did not return the global logger")
+	}
+
+	ctx := WithLogger(context.Background(), logger)
+	if got := FromContext(ctx); got != logger {
+		t.Error("FromContext did not return the logger stored with WithLogger")
+	}
+	child := logger.Named("child")
+	if got := FromContext(WithLogger(ctx, child)); got != child {
+		t.Error("FromContext did not return the innermost logger")
+	}
+}
+
+func TestCaller(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+			calls := map[string]func(){
+				"Info":     func() { logger.Info("x") },
+				"Errorf":   func() { logger.Errorf("%s", "x") },
+				"InfoCtx":  func() { logger.InfoCtx(context.Background(), "x") },
+				"ErrorErr": func() { logger.ErrorErr(&testErr{msg: "x"}, "x") },
+				"Log":      func() { logger.Log(WarnLevel, "x") },
+				"With":     func() { logger.With("k", "v").Named("n").Warn("x") },
+			}
+			for name, call := range calls {
+				call()
+				entry, _ := out.last(t)
+				if entry.Caller == nil || filepath.Base(entry.Caller.File) != "adapter_test.go" {
+					t.Errorf("%s: caller = %+v, want adapter_test.go", name, entry.Caller)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerGenCaller(t *testing.T) {
+	var buf bytes.Buffer
+	gen := func(LogLevel, EncoderType) AbsLog {
+		enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+			MessageKey:   "msg",
+			CallerKey:    "caller",
+			EncodeCaller: zapcore.ShortCallerEncoder,
+		})
+		core := zapcore.NewCore(enc, zapcore.AddSync(&buf), zap.DebugLevel)
+		return NewLoggerAdapter(zap.New(core, zap.AddCaller(), zap.AddCallerSkip(2)).Sugar())
+	}
+	logger := GetAbsLogBuilder().LoggerGen(gen).Build()
+
+	logger.Info("custom")
+	logger.With("k", "v").Warnf("custom %d", 2)
+
+	dec := json.NewDecoder(&buf)
+	for range 2 {
+		var line struct {
+			Msg    string `json:"msg"`
+			Caller string `json:"caller"`
+		}
+		if err := dec.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		if file, _, _ := strings.Cut(filepath.Base(line.Caller), ":"); file != "adapter_test.go" {
+			t.Errorf("%q: caller = %q, want adapter_test.go", line.Msg, line.Caller)
+		}
+	}
+}
@@ -1,14 +1,28 @@
 package abslog
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
 
 // LogLevel represents the severity level of log messages.
+// Higher values are more severe. Built-in levels are spaced by 10 so that
+// custom levels can be registered between them (see RegisterLevel).
+//
+// Before TraceLevel was added, the built-in levels went from DebugLevel = 1 to
+// FatalLevel = 6. These legacy values are still accepted wherever a level is
+// given, e.g. LogLevel(2) is InfoLevel, so that levels stored or configured as
+// numbers keep their meaning.
 type LogLevel int8
 
 // Log level constants defining the severity of log messages.
 const (
+	// TraceLevel is used for very chatty messages, such as wire-level logs.
+	TraceLevel LogLevel = (iota + 1) * 10
 	// DebugLevel is used for debug messages, typically only enabled during development.
-	DebugLevel LogLevel = iota + 1
+	DebugLevel
 	// InfoLevel is used for general informational messages.
 	InfoLevel
 	// WarnLevel is used for warning messages that indicate potential issues.
@@ -30,6 +44,21 @@ const (
 	ConsoleEncoder EncoderType = iota + 1
 	// JSONEncoder formats logs as JSON for structured logging.
 	JSONEncoder
+	// LogfmtEncoder formats logs as logfmt key=value lines, with context values as pairs.
+	LogfmtEncoder
+	// GELFEncoder formats logs as GELF 1.1 JSON messages for Graylog, with context values as additional fields.
+	GELFEncoder
+	// GCPEncoder formats logs as Google Cloud Logging structured logs, with severities,
+	// source locations, trace context, HTTP requests and labels in their special fields.
+	GCPEncoder
+	// ECSEncoder formats logs as Elastic Common Schema JSON documents for Elasticsearch.
+	ECSEncoder
+	// ColorConsoleEncoder formats logs for the console like ConsoleEncoder, with the same
+	// colors on both backends when writing to a terminal and NO_COLOR is not set.
+	ColorConsoleEncoder
+	// DevelopmentEncoder formats logs for local development, with relative timestamps,
+	// aligned columns and fields, context values and stack traces on indented lines.
+	DevelopmentEncoder
 )
 
 // LoggerType represents the underlying logging library to use.
@@ -49,6 +78,10 @@ const defaultEncoderType = ConsoleEncoder
 
 // LoggerGen is a function type that creates an AbsLog instance
 // with the specified log level and encoder type.
+//
+// Loggers wrapped with NewLoggerAdapter are called through two abslog frames,
+// the AbsLog method and the adapter's log method, so loggers reporting the
+// call site must skip two frames, e.g. with zap.AddCallerSkip(2).
 type LoggerGen func(logLevel LogLevel, encoder EncoderType) AbsLog
 
 // AbsLogBuilder is the interface that wraps the Builder methods to create a new AbsLog.
@@ -58,6 +91,14 @@ type AbsLogBuilder interface {
 	LoggerType(loggerType LoggerType) AbsLogBuilder
 	EncoderType(encoderType EncoderType) AbsLogBuilder
 	ContextKey(key string) AbsLogBuilder
+	ContextSeparator(separator string) AbsLogBuilder
+	RedactKeys(keys ...string) AbsLogBuilder
+	RedactPatterns(patterns ...*regexp.Regexp) AbsLogBuilder
+	RedactFunc(fn RedactFunc) AbsLogBuilder
+	RedactMask(mask string) AbsLogBuilder
+	Hooks(hooks ...Hook) AbsLogBuilder
+	Output(output Output) AbsLogBuilder
+	Development() AbsLogBuilder
 	BuildAndSetAsGlobal() AbsLog
 	Build() AbsLog
 }
@@ -69,6 +110,10 @@ type absBuilder struct {
 	loggerType  LoggerType
 	encoderType EncoderType
 	contextKey  string
+	contextSep  string
+	redactor    *redactor
+	hooks       []Hook
+	output      Output
 }
 
 // GetAbsLogBuilder returns a new AbsLog builder.
@@ -78,12 +123,13 @@ func GetAbsLogBuilder() AbsLogBuilder {
 		loggerType:  defaultLoggerType,
 		encoderType: defaultEncoderType,
 		contextKey:  "", // Empty means use global setting
+		contextSep:  "", // Empty means use global setting
 	}
 }
 
 // LogLevel sets the log level for the AbsLog.
 func (builder *absBuilder) LogLevel(level LogLevel) AbsLogBuilder {
-	builder.logLevel = level
+	builder.logLevel = normalizeLevel(level)
 	return builder
 }
 
@@ -106,21 +152,111 @@ func (builder *absBuilder) EncoderType(encoderType EncoderType) AbsLogBuilder {
 }
 
 // ContextKey sets the context key for this logger instance.
-// If empty, the global context key setting will be used.
-// This allows different logger instances to use different context keys.
+// If empty or only whitespace, the global context key setting will be used.
+// This allows different logger instances to use different context keys
+// without changing the key used by any other logger.
 func (builder *absBuilder) ContextKey(key string) AbsLogBuilder {
-	builder.contextKey = key
+	builder.contextKey = strings.TrimSpace(key)
+	return builder
+}
+
+// ContextSeparator sets the separator between context values and log messages
+// for this logger instance.
+// If empty or only whitespace, the global context separator setting will be used.
+func (builder *absBuilder) ContextSeparator(separator string) AbsLogBuilder {
+	if strings.TrimSpace(separator) == "" {
+		separator = ""
+	}
+	builder.contextSep = separator
+	return builder
+}
+
+// RedactKeys masks the values of context values and structured fields whose key
+// matches one of the given keys, compared case-insensitively.
+func (builder *absBuilder) RedactKeys(keys ...string) AbsLogBuilder {
+	for _, key := range keys {
+		builder.getRedactor().keys[strings.ToLower(key)] = struct{}{}
+	}
+	return builder
+}
+
+// RedactPatterns masks every match of the given patterns in messages,
+// context values and structured field values.
+func (builder *absBuilder) RedactPatterns(patterns ...*regexp.Regexp) AbsLogBuilder {
+	r := builder.getRedactor()
+	r.patterns = append(r.patterns, patterns...)
+	return builder
+}
+
+// RedactFunc adds a custom redaction function applied to messages, context
+// values and structured field values.
+func (builder *absBuilder) RedactFunc(fn RedactFunc) AbsLogBuilder {
+	r := builder.getRedactor()
+	r.funcs = append(r.funcs, fn)
+	return builder
+}
+
+// RedactMask sets the text that replaces redacted data.
+// If mask is empty, the default mask "[REDACTED]" will be used.
+func (builder *absBuilder) RedactMask(mask string) AbsLogBuilder {
+	if mask == "" {
+		mask = defaultRedactionMask
+	}
+	builder.getRedactor().mask = mask
+	return builder
+}
+
+// Hooks adds hooks run for every entry of the AbsLog, after the global hooks
+// registered with RegisterHook.
+func (builder *absBuilder) Hooks(hooks ...Hook) AbsLogBuilder {
+	for _, hook := range hooks {
+		if hook != nil {
+			builder.hooks = append(builder.hooks, hook)
+		}
+	}
+	return builder
+}
+
+// Output sets the output entries are written to, in place of stdout and stderr.
+// It is only supported by the built-in logger types, not with LoggerGen.
+// If nil, entries are written to stdout and stderr.
+func (builder *absBuilder) Output(output Output) AbsLogBuilder {
+	builder.output = output
 	return builder
 }
 
+// Development configures the builder for local development: debug level and
+// DevelopmentEncoder. Later calls to LogLevel and EncoderType override it.
+func (builder *absBuilder) Development() AbsLogBuilder {
+	builder.logLevel = DebugLevel
+	builder.encoderType = DevelopmentEncoder
+	return builder
+}
+
+// getRedactor returns the builder redactor, creating it on first use.
+func (builder *absBuilder) getRedactor() *redactor {
+	if builder.redactor == nil {
+		builder.redactor = newRedactor()
+	}
+	return builder.redactor
+}
+
 // Build builds a new AbsLog.
 func (builder *absBuilder) Build() AbsLog {
 	return builder.build()
 }
 
 // BuildAndSetAsGlobal builds a new AbsLogger and sets it as the global AbsLog.
+// The context key and separator configured on the builder, if any, also become
+// the global ones, so that GetCtxKey and WithValues match the global logger.
 func (builder *absBuilder) BuildAndSetAsGlobal() AbsLog {
 	l := builder.build()
+	if builder.contextKey != "" {
+		SetCtxKey(builder.contextKey)
+	}
+	if builder.contextSep != "" {
+		SetCtxSeparator(builder.contextSep)
+	}
 	SetLogger(l)
 	return l
 }
@@ -129,27 +265,46 @@ func (builder *absBuilder) BuildAndSetAsGlobal() AbsLog {
 // It validates the encoder type and sets up the appropriate logger generator if needed.
 func (builder *absBuilder) build() AbsLog {
 	// Validate encoder type
-	if builder.encoderType != ConsoleEncoder && builder.encoderType != JSONEncoder {
+	if _, ok := encoderTypeNames[builder.encoderType]; !ok {
 		panic(fmt.Sprintf("Invalid encoder type: %d", builder.encoderType))
 	}
 
-	// Apply context key configuration if specified
-	if builder.contextKey != "" {
-		SetCtxKey(builder.contextKey)
+	// Outputs are written by the built-in logger types only
+	if builder.output != nil && builder.loggerGen != nil {
+		panic("Output is not supported with a custom LoggerGen")
 	}
 
-	// Set default logger generator if not provided
-	if builder.loggerGen == nil {
+	// Use the default logger generator if not provided
+	loggerGen := builder.loggerGen
+	if loggerGen == nil {
 		switch builder.loggerType {
 		case ZapLogger:
-			builder.loggerGen = getZapLogger
+			loggerGen = func(logLevel LogLevel, encoder EncoderType) AbsLog {
+				return buildZapLogger(logLevel, encoder, builder.output)
+			}
 		case LogrusLogger:
-			builder.loggerGen = getLogrusLogger
+			loggerGen = func(logLevel LogLevel, encoder EncoderType) AbsLog {
+				return buildLogrusLogger(logLevel, encoder, builder.output)
+			}
 		default:
 			panic(fmt.Sprintf("AbsLog type '%d' is not supported", int(builder.loggerType)))
 		}
 	}
 
-	// Create and return the logger instance
-	return builder.loggerGen(builder.logLevel, builder.encoderType)
+	// Create the logger instance
+	l := loggerGen(builder.logLevel, builder.encoderType)
+
+	// Attach redaction rules, context handling and hooks to loggers wrapped in a LoggerAdapter
+	if a, ok := l.(*LoggerAdapter); ok {
+		a.ctxKey = ContextKeyType(builder.contextKey)
+		a.ctxSeparator = builder.contextSep
+		if !builder.redactor.empty() {
+			a.redactor = builder.redactor.clone()
+		}
+		// Structured encoders and some outputs carry context values as fields rather than as a message prefix
+		a.ctxAsFields = encoderCtxAsFields(builder.encoderType) || wantsCtxFields(builder.output)
+		a.hooks = slices.Clone(builder.hooks)
+	}
+
+	return l
 }
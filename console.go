@@ -0,0 +1,112 @@
+package abslog
+
+import (
+	"os"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+)
+
+// ANSI escape codes used by the color console encoder
+const (
+	ansiReset   = "\x1b[0m"
+	ansiBold    = "\x1b[1m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// consoleLevelWidth is the width the level names are padded to.
+const consoleLevelWidth = 5
+
+// colorEnabled reports whether colors should be written to f: when f is a
+// terminal, NO_COLOR is not set (see https://no-color.org) and TERM is not "dumb".
+func colorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+// isTerminal reports whether f is a character device, such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// levelColor returns the color of a level: magenta for trace and debug, blue
+// for info, yellow for warn, red for error and bold red for panic and fatal.
+// Custom levels use the color of the closest built-in level below them.
+func levelColor(level LogLevel) string {
+	switch baseLevel(level) {
+	case TraceLevel, DebugLevel:
+		return ansiMagenta
+	case InfoLevel:
+		return ansiBlue
+	case WarnLevel:
+		return ansiYellow
+	case ErrorLevel:
+		return ansiRed
+	default:
+		return ansiBold + ansiRed
+	}
+}
+
+// appendPainted writes s, wrapped in the ANSI code and a reset when color is set.
+func appendPainted(buf *buffer.Buffer, color bool, code, s string) {
+	if !color {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendString(code)
+	buf.AppendString(s)
+	buf.AppendString(ansiReset)
+}
+
+// terminalEncoder returns the entryEncoder of an encoder type written for
+// terminals, ColorConsoleEncoder or DevelopmentEncoder.
+func terminalEncoder(encoder EncoderType, color bool) entryEncoder {
+	if encoder == DevelopmentEncoder {
+		return developmentEncoder(color)
+	}
+	return colorConsoleEncoder(color)
+}
+
+// colorConsoleEncoder returns an entryEncoder writing entries as console lines:
+// timestamp, level, caller, message and key=value fields, followed by the
+// stack trace on the next lines. With color, levels are colored, timestamps,
+// callers and stack traces dimmed and field keys highlighted.
+func colorConsoleEncoder(color bool) entryEncoder {
+	return func(buf *buffer.Buffer, entry *Entry, stack string) {
+		appendPainted(buf, color, ansiDim, formatEntryTime(entry.Time))
+		buf.AppendByte(' ')
+
+		name := strings.ToUpper(levelName(entry.Level))
+		if pad := consoleLevelWidth - len(name); pad > 0 {
+			name += strings.Repeat(" ", pad)
+		}
+		appendPainted(buf, color, levelColor(entry.Level), name)
+
+		if entry.Caller != nil {
+			buf.AppendByte(' ')
+			appendPainted(buf, color, ansiDim, shortCaller(entry.Caller))
+		}
+		buf.AppendByte(' ')
+		buf.AppendString(entry.Message)
+
+		for _, f := range entry.Fields {
+			buf.AppendByte(' ')
+			appendPainted(buf, color, ansiCyan, logfmtKey(f.Key))
+			buf.AppendByte('=')
+			appendLogfmtValue(buf, f.Value)
+		}
+		if stack != "" {
+			buf.AppendByte('\n')
+			appendPainted(buf, color, ansiDim, stack)
+		}
+		buf.AppendByte('\n')
+	}
+}
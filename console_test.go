@@ -0,0 +1,155 @@
+package abslog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/buffer"
+)
+
+func TestColorConsoleEncoder(t *testing.T) {
+	registerTestLevels()
+
+	tests := []struct {
+		name string
+		log  func(logger AbsLog)
+		// want matches the first line without its timestamp and caller
+		want string
+		// stack reports whether the stack trace follows the first line
+		stack bool
+	}{
+		{"info", func(l AbsLog) { l.Info("hello world") }, `^INFO  hello world$`, false},
+		{"padded level", func(l AbsLog) { l.Warn("m") }, `^WARN  m$`, false},
+		{"custom level", func(l AbsLog) { l.Log(testNoticeLevel, "m") }, `^NOTICE m$`, false},
+		{"fields", func(l AbsLog) { l.With("user", "ann", "a b", "x y").Debug("m") }, `^DEBUG m a_b="x y" user=ann$`, false},
+		{"context values", func(l AbsLog) { l.InfoCtx(WithValues(t.Context(), "request_id", "r1"), "m") }, `request_id=r1`, false},
+		{"error", func(l AbsLog) { l.ErrorErr(errors.New("boom"), "failed") }, `^ERROR failed error=boom error_type=\*errors.errorString$`, true},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, ColorConsoleEncoder)
+				tt.log(logger)
+				_, line := out.last(t)
+
+				// Colors are never written to an output
+				if strings.Contains(line, "\x1b[") {
+					t.Errorf("line %q holds ANSI codes", line)
+				}
+				first, stack, _ := strings.Cut(strings.TrimSuffix(line, "\n"), "\n")
+				ts, rest, _ := strings.Cut(first, " ")
+				if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+					t.Errorf("line %q does not start with a timestamp: %v", line, err)
+				}
+				caller := regexp.MustCompile(` \S*/console_test.go:\d+ `)
+				if !caller.MatchString(rest) {
+					t.Errorf("line %q has no caller", line)
+				}
+				rest = caller.ReplaceAllString(rest, " ")
+				if !regexp.MustCompile(tt.want).MatchString(rest) {
+					t.Errorf("line %q, want a match of %s", rest, tt.want)
+				}
+				if hasStack := strings.Contains(stack, "TestColorConsoleEncoder"); hasStack != tt.stack {
+					t.Errorf("stack %q, want a stack trace %v", stack, tt.stack)
+				}
+			})
+		}
+	}
+}
+
+func TestColorConsoleEncoderColors(t *testing.T) {
+	entry := &Entry{
+		Level:   WarnLevel,
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Message: "m",
+		Caller:  &runtime.Frame{File: "/src/app/main.go", Line: 7},
+		Fields:  []Field{{Key: "user", Value: "ann"}},
+	}
+	tests := []struct {
+		name  string
+		color bool
+		stack string
+		want  string
+	}{
+		{"plain", false, "", "2024-05-01T12:00:00.000Z WARN  app/main.go:7 m user=ann\n"},
+		{"color", true, "", "\x1b[2m2024-05-01T12:00:00.000Z\x1b[0m \x1b[33mWARN \x1b[0m \x1b[2mapp/main.go:7\x1b[0m m \x1b[36muser\x1b[0m=ann\n"},
+		{"color with stack", true, "main.main\n\tmain.go:7",
+			"\x1b[2m2024-05-01T12:00:00.000Z\x1b[0m \x1b[33mWARN \x1b[0m \x1b[2mapp/main.go:7\x1b[0m m \x1b[36muser\x1b[0m=ann\n\x1b[2mmain.main\n\tmain.go:7\x1b[0m\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := buffer.NewPool().Get()
+			colorConsoleEncoder(tt.color)(buf, entry, tt.stack)
+			if got := buf.String(); got != tt.want {
+				t.Errorf("encoded %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelColor(t *testing.T) {
+	registerTestLevels()
+
+	tests := []struct {
+		level LogLevel
+		want  string
+	}{
+		{TraceLevel, ansiMagenta},
+		{DebugLevel, ansiMagenta},
+		{InfoLevel, ansiBlue},
+		{testNoticeLevel, ansiBlue},
+		{WarnLevel, ansiYellow},
+		{ErrorLevel, ansiRed},
+		{testAuditLevel, ansiRed},
+		{PanicLevel, ansiBold + ansiRed},
+		{FatalLevel, ansiBold + ansiRed},
+	}
+	for _, tt := range tests {
+		if got := levelColor(tt.level); got != tt.want {
+			t.Errorf("levelColor(%v) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	// The null device is a character device like terminals
+	device, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+
+	tests := []struct {
+		name    string
+		f       *os.File
+		noColor string
+		term    string
+		want    bool
+	}{
+		{"file", file, "", "xterm", false},
+		{"device", device, "", "xterm", runtime.GOOS != "windows"},
+		{"NO_COLOR", device, "1", "xterm", false},
+		{"dumb terminal", device, "", "dumb", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			t.Setenv("TERM", tt.term)
+			if got := colorEnabled(tt.f); got != tt.want {
+				t.Errorf("colorEnabled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package abslog
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ctxValuesKey is the field key grouping context values without a key
+// (e.g. []string or string) when context values are emitted as fields.
+const ctxValuesKey = "context"
+
+// ctxFormatter converts a context value into fields.
+type ctxFormatter func(value any) []Field
+
+// ctxFormatters holds the registered context value formatters.
+var ctxFormatters = struct {
+	sync.RWMutex
+	// byType holds formatters registered for concrete types
+	byType map[reflect.Type]ctxFormatter
+	// byInterface holds formatters registered for interface types, in registration order
+	byInterface []ctxInterfaceFormatter
+}{byType: make(map[reflect.Type]ctxFormatter)}
+
+// ctxInterfaceFormatter is a formatter registered for an interface type.
+type ctxInterfaceFormatter struct {
+	typ    reflect.Type
+	format ctxFormatter
+}
+
+// RegisterCtxFormatter registers fn to render context values of type T.
+// Fields with an empty key are rendered as bare values. T may be an interface
+// type, in which case fn is used for every value implementing it that has no
+// formatter registered for its concrete type. Registering a formatter for a
+// type again replaces the previous one.
+//
+// Without a registered formatter, context values are rendered as follows:
+//   - map[string]any, map[string]string and other maps with string keys: one field per entry, sorted by key
+//   - structs and pointers to structs: one field per exported struct field, named after its json tag if any
+//   - []string, []any and other slices: one bare value per item
+//   - fmt.Stringer and error: a bare value with their text
+//   - any other value: a bare value
+func RegisterCtxFormatter[T any](fn func(value T) []Field) {
+	typ := reflect.TypeFor[T]()
+	format := func(value any) []Field { return fn(value.(T)) }
+
+	ctxFormatters.Lock()
+	defer ctxFormatters.Unlock()
+
+	if typ.Kind() != reflect.Interface {
+		ctxFormatters.byType[typ] = format
+		return
+	}
+	for i, f := range ctxFormatters.byInterface {
+		if f.typ == typ {
+			ctxFormatters.byInterface[i].format = format
+			return
+		}
+	}
+	ctxFormatters.byInterface = append(ctxFormatters.byInterface, ctxInterfaceFormatter{typ: typ, format: format})
+}
+
+// registeredCtxFormatter returns the formatter registered for the dynamic type of value.
+func registeredCtxFormatter(value any) (ctxFormatter, bool) {
+	typ := reflect.TypeOf(value)
+
+	ctxFormatters.RLock()
+	defer ctxFormatters.RUnlock()
+
+	if format, ok := ctxFormatters.byType[typ]; ok {
+		return format, true
+	}
+	for _, f := range ctxFormatters.byInterface {
+		if typ.Implements(f.typ) {
+			return f.format, true
+		}
+	}
+	return nil, false
+}
+
+// ctxFields returns the context values stored in ctx under the given key as fields.
+func ctxFields(ctx context.Context, key ContextKeyType) []Field {
+	if ctx == nil {
+		return nil
+	}
+	value := ctx.Value(key)
+	if value == nil {
+		return nil
+	}
+	if format, ok := registeredCtxFormatter(value); ok {
+		return format(value)
+	}
+	return defaultCtxFields(value)
+}
+
+// defaultCtxFields converts context values without a registered formatter into fields.
+func defaultCtxFields(value any) []Field {
+	// Fast paths for the most common types
+	switch v := value.(type) {
+	case map[string]any:
+		fields := make([]Field, 0, len(v))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			fields = append(fields, Field{Key: k, Value: v[k]})
+		}
+		return fields
+	case map[string]string:
+		fields := make([]Field, 0, len(v))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			fields = append(fields, Field{Key: k, Value: v[k]})
+		}
+		return fields
+	case []Field:
+		return v
+	case []string:
+		fields := make([]Field, len(v))
+		for i, item := range v {
+			fields[i] = Field{Value: item}
+		}
+		return fields
+	case []any:
+		fields := make([]Field, len(v))
+		for i, item := range v {
+			fields[i] = Field{Value: item}
+		}
+		return fields
+	case string:
+		return []Field{{Value: v}}
+	case fmt.Stringer:
+		return []Field{{Value: v.String()}}
+	case error:
+		return []Field{{Value: v.Error()}}
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		fields := make([]Field, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			fields = append(fields, Field{Key: k.String(), Value: rv.MapIndex(k).Interface()})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+		return fields
+	case reflect.Struct:
+		return structFields(rv)
+	case reflect.Slice, reflect.Array:
+		fields := make([]Field, rv.Len())
+		for i := range rv.Len() {
+			fields[i] = Field{Value: rv.Index(i).Interface()}
+		}
+		return fields
+	}
+
+	return []Field{{Value: value}}
+}
+
+// structFields returns one field per exported field of the struct value rv,
+// named after the json tag when present. Fields tagged with "-" are skipped.
+func structFields(rv reflect.Value) []Field {
+	typ := rv.Type()
+	fields := make([]Field, 0, typ.NumField())
+	for i := range typ.NumField() {
+		sf := typ.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := sf.Name
+		if tag, _, _ := strings.Cut(sf.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			key = tag
+		}
+		fields = append(fields, Field{Key: key, Value: rv.Field(i).Interface()})
+	}
+	return fields
+}
+
+// renderCtxFields renders context fields as "[key1=value1, value2]" followed
+// by separator. Fields without a key are rendered as bare values.
+func renderCtxFields(fields []Field, separator string) string {
+	var builder strings.Builder
+	builder.WriteString("[")
+	for i, f := range fields {
+		if i > 0 {
+			builder.WriteString(", ")
+		}
+		if f.Key != "" {
+			builder.WriteString(f.Key)
+			builder.WriteString("=")
+		}
+		builder.WriteString(fmt.Sprint(f.Value))
+	}
+	builder.WriteString("]")
+	builder.WriteString(separator)
+	return builder.String()
+}
+
+// keyedCtxFields groups the context fields without a key under the "context"
+// key so that they can be emitted as structured fields.
+func keyedCtxFields(fields []Field) []Field {
+	keyed := make([]Field, 0, len(fields)+1)
+	var bare []any
+	for _, f := range fields {
+		if f.Key == "" {
+			bare = append(bare, f.Value)
+			continue
+		}
+		keyed = append(keyed, f)
+	}
+
+	switch len(bare) {
+	case 0:
+	case 1:
+		keyed = append(keyed, Field{Key: ctxValuesKey, Value: bare[0]})
+	default:
+		keyed = append(keyed, Field{Key: ctxValuesKey, Value: bare})
+	}
+	return keyed
+}
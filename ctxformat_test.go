@@ -0,0 +1,173 @@
+package abslog
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// requestInfo is a struct context value rendered field by field.
+type requestInfo struct {
+	ID     string `json:"request_id"`
+	Method string
+	Secret string `json:"-"`
+	hidden string
+}
+
+// tenant is a context value implementing fmt.Stringer.
+type tenant struct {
+	name string
+}
+
+func (t tenant) String() string {
+	return "tenant:" + t.name
+}
+
+// session is a context value with a formatter registered for its type.
+type session struct {
+	user string
+	role string
+}
+
+// identity is an interface with a formatter registered for it.
+type identity interface {
+	Identity() string
+}
+
+// apiKey implements identity.
+type apiKey string
+
+func (k apiKey) Identity() string {
+	return "key-" + string(k)
+}
+
+func TestCtxFormatters(t *testing.T) {
+	RegisterCtxFormatter(func(s session) []Field {
+		return []Field{{Key: "user", Value: s.user}, {Key: "role", Value: s.role}}
+	})
+	RegisterCtxFormatter(func(i identity) []Field {
+		return []Field{{Key: "identity", Value: i.Identity()}}
+	})
+
+	tests := []struct {
+		name  string
+		value any
+		// wantPrefix is the message prefix written by the console encoder
+		wantPrefix string
+		// wantFields are the fields written by the JSON encoder
+		wantFields map[string]string
+	}{
+		{
+			name:       "map[string]any",
+			value:      map[string]any{"b": 2, "a": "x"},
+			wantPrefix: "[a=x, b=2]",
+			wantFields: map[string]string{"a": "x", "b": "2"},
+		},
+		{
+			name:       "map[string]string",
+			value:      map[string]string{"user": "ann"},
+			wantPrefix: "[user=ann]",
+			wantFields: map[string]string{"user": "ann"},
+		},
+		{
+			name:       "other string keyed map",
+			value:      map[string]int{"retries": 3, "attempt": 1},
+			wantPrefix: "[attempt=1, retries=3]",
+			wantFields: map[string]string{"attempt": "1", "retries": "3"},
+		},
+		{
+			name:       "[]string",
+			value:      []string{"r1", "ann"},
+			wantPrefix: "[r1, ann]",
+			wantFields: map[string]string{ctxValuesKey: "[r1 ann]"},
+		},
+		{
+			name:       "[]any",
+			value:      []any{"r1", 2},
+			wantPrefix: "[r1, 2]",
+			wantFields: map[string]string{ctxValuesKey: "[r1 2]"},
+		},
+		{
+			name:       "string",
+			value:      "r1",
+			wantPrefix: "[r1]",
+			wantFields: map[string]string{ctxValuesKey: "r1"},
+		},
+		{
+			name:       "struct",
+			value:      requestInfo{ID: "r1", Method: "GET", Secret: "s", hidden: "h"},
+			wantPrefix: "[request_id=r1, Method=GET]",
+			wantFields: map[string]string{"request_id": "r1", "Method": "GET"},
+		},
+		{
+			name:       "struct pointer",
+			value:      &requestInfo{ID: "r2"},
+			wantPrefix: "[request_id=r2, Method=]",
+			wantFields: map[string]string{"request_id": "r2"},
+		},
+		{
+			name:       "stringer",
+			value:      tenant{name: "acme"},
+			wantPrefix: "[tenant:acme]",
+			wantFields: map[string]string{ctxValuesKey: "tenant:acme"},
+		},
+		{
+			name:       "error",
+			value:      fmt.Errorf("denied"),
+			wantPrefix: "[denied]",
+			wantFields: map[string]string{ctxValuesKey: "denied"},
+		},
+		{
+			name:       "registered type",
+			value:      session{user: "ann", role: "admin"},
+			wantPrefix: "[user=ann, role=admin]",
+			wantFields: map[string]string{"user": "ann", "role": "admin"},
+		},
+		{
+			name:       "registered interface",
+			value:      apiKey("42"),
+			wantPrefix: "[identity=key-42]",
+			wantFields: map[string]string{"identity": "key-42"},
+		},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				ctx := context.WithValue(context.Background(), GetCtxKey(), tt.value)
+
+				console, out := newCaptureLogger(backend.typ, ConsoleEncoder)
+				console.InfoCtx(ctx, "msg")
+				entry, _ := out.last(t)
+				if want := tt.wantPrefix + GetCtxSeparator() + " msg"; entry.Message != want {
+					t.Errorf("console message = %q, want %q", entry.Message, want)
+				}
+
+				structured, out := newCaptureLogger(backend.typ, JSONEncoder)
+				structured.InfoCtx(ctx, "msg")
+				entry, _ = out.last(t)
+				if entry.Message != "msg" {
+					t.Errorf("JSON message = %q, want %q", entry.Message, "msg")
+				}
+				for key, want := range tt.wantFields {
+					assertField(t, entry, key, want)
+				}
+			})
+		}
+	}
+}
+
+func TestCtxFormatterNilPointer(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, ConsoleEncoder)
+			ctx := context.WithValue(context.Background(), GetCtxKey(), (*requestInfo)(nil))
+			logger.InfoCtx(ctx, "msg")
+
+			entry, _ := out.last(t)
+			if entry.Message != "msg" {
+				t.Errorf("message = %q, want %q", entry.Message, "msg")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package abslog
+
+import "context"
+
+// loggerCtxKey is the context key under which WithLogger stores an AbsLog.
+type loggerCtxKey struct{}
+
+// WithLogger returns a copy of ctx carrying the given logger, so request-scoped
+// loggers (e.g. built with With or Named) can flow through call stacks.
+func WithLogger(ctx context.Context, logger AbsLog) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by WithLogger.
+// If ctx is nil or carries no logger, the global logger is returned.
+func FromContext(ctx context.Context) AbsLog {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerCtxKey{}).(AbsLog); ok && logger != nil {
+			return logger
+		}
+	}
+	return globalLogger
+}
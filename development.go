@@ -0,0 +1,133 @@
+package abslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+)
+
+// Column widths of the development encoder header lines
+const (
+	devTimeWidth   = 10
+	devCallerWidth = 24
+	// devIndent starts the continuation lines holding the fields
+	devIndent = "    "
+)
+
+// processStart is the reference of the relative timestamps of the development encoder.
+var processStart = time.Now()
+
+// developmentEncoder returns an entryEncoder writing entries for local
+// development. The header line holds the time elapsed since the process
+// started, the level, the caller and the message in aligned columns. Fields,
+// including context values, follow on indented lines with aligned keys:
+// lists hold one item per line, structured values are written as indented
+// JSON and multiline values, such as error stacks, continue on the next lines.
+// The stack trace of entries at error level and above comes last.
+// Colors are used as in colorConsoleEncoder.
+func developmentEncoder(color bool) entryEncoder {
+	return func(buf *buffer.Buffer, entry *Entry, stack string) {
+		t := entry.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		elapsed := "+" + t.Sub(processStart).Round(time.Millisecond).String()
+		appendPainted(buf, color, ansiDim, fmt.Sprintf("%*s", devTimeWidth, elapsed))
+		buf.AppendByte(' ')
+		appendPainted(buf, color, levelColor(entry.Level), fmt.Sprintf("%-*s", consoleLevelWidth, strings.ToUpper(levelName(entry.Level))))
+		buf.AppendByte(' ')
+		caller := ""
+		if entry.Caller != nil {
+			caller = shortCaller(entry.Caller)
+		}
+		appendPainted(buf, color, ansiDim, fmt.Sprintf("%-*s", devCallerWidth, caller))
+		buf.AppendByte(' ')
+		buf.AppendString(strings.ReplaceAll(entry.Message, "\n", "\n"+devIndent))
+		buf.AppendByte('\n')
+
+		width := 0
+		for _, f := range entry.Fields {
+			width = max(width, len(f.Key))
+		}
+		if stack != "" {
+			width = max(width, len(stackKey))
+		}
+		// field writes a key and its value lines, painted with code if not empty
+		field := func(key string, lines []string, code string) {
+			buf.AppendString(devIndent)
+			appendPainted(buf, color, ansiCyan, fmt.Sprintf("%-*s", width, key))
+			for i, line := range lines {
+				if i > 0 {
+					buf.AppendByte('\n')
+					buf.AppendString(devIndent + strings.Repeat(" ", width))
+				}
+				buf.AppendString("  ")
+				appendPainted(buf, color && code != "", code, line)
+			}
+			buf.AppendByte('\n')
+		}
+
+		for _, f := range entry.Fields {
+			field(f.Key, devValueLines(f.Value), "")
+		}
+		if stack != "" {
+			field(stackKey, strings.Split(strings.ReplaceAll(stack, "\t", "  "), "\n"), ansiDim)
+		}
+	}
+}
+
+// devValueLines returns the lines a field value is written on by the development encoder.
+func devValueLines(value any) []string {
+	switch v := value.(type) {
+	case nil:
+		return []string{"<nil>"}
+	case string:
+		if v == "" {
+			return []string{`""`}
+		}
+		return strings.Split(strings.ReplaceAll(v, "\t", "  "), "\n")
+	case error:
+		return strings.Split(v.Error(), "\n")
+	case time.Time:
+		// Before fmt.Stringer, which time.Time implements
+		return []string{v.Format(time.RFC3339Nano)}
+	case fmt.Stringer:
+		return strings.Split(v.String(), "\n")
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return devListLines(items)
+	case []any:
+		return devListLines(v)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return []string{fmt.Sprint(v)}
+	}
+	if b, err := json.MarshalIndent(value, "", "  "); err == nil {
+		return strings.Split(string(b), "\n")
+	}
+	return strings.Split(fmt.Sprintf("%+v", value), "\n")
+}
+
+// devListLines returns the lines of a list, one "- item" per item, with the
+// continuation lines of multiline items indented.
+func devListLines(items []any) []string {
+	if len(items) == 0 {
+		return []string{"[]"}
+	}
+	var lines []string
+	for _, item := range items {
+		for i, line := range devValueLines(item) {
+			if i == 0 {
+				lines = append(lines, "- "+line)
+			} else {
+				lines = append(lines, "  "+line)
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,180 @@
+package abslog
+
+import (
+	"errors"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/buffer"
+)
+
+func TestDevelopmentEncoder(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger AbsLog)
+		// header matches the first line without the elapsed time and caller
+		header string
+		// body is the following lines, without the stack trace
+		body string
+		// stack reports whether the stack trace comes last
+		stack bool
+	}{
+		{"message", func(l AbsLog) { l.Info("hello") }, `^INFO  hello$`, "", false},
+		{"multiline message", func(l AbsLog) { l.Warn("first\nsecond") }, `^WARN  first$`, "    second\n", false},
+		{"aligned fields", func(l AbsLog) { l.With("user", "ann", "n", 3).Debug("m") }, `^DEBUG m$`,
+			"    n     3\n    user  ann\n", false},
+		{"list and object", func(l AbsLog) { l.With("tags", []string{"a", "b"}, "cfg", map[string]any{"k": 1}).Info("m") }, `^INFO  m$`,
+			"    cfg   {\n            \"k\": 1\n          }\n    tags  - a\n          - b\n", false},
+		{"context values", func(l AbsLog) { l.InfoCtx(WithValues(t.Context(), "request_id", "r1"), "m") }, `^INFO  m$`,
+			"    request_id  r1\n", false},
+		{"error", func(l AbsLog) { l.ErrorErr(errors.New("boom"), "failed") }, `^ERROR failed$`,
+			"    error       boom\n    error_type  *errors.errorString\n", true},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, DevelopmentEncoder)
+				tt.log(logger)
+				_, line := out.last(t)
+
+				if strings.Contains(line, "\x1b[") {
+					t.Errorf("line %q holds ANSI codes", line)
+				}
+				header, body, _ := strings.Cut(line, "\n")
+				prefix := regexp.MustCompile(`^ *\+\S+ \S+ +\S*/development_test.go:\d+ +`)
+				if !prefix.MatchString(header) {
+					t.Fatalf("header %q does not start with the elapsed time, level and caller", header)
+				}
+				// Columns are aligned
+				if i := strings.Index(header, "+"); i >= devTimeWidth {
+					t.Errorf("header %q, want the elapsed time right-aligned", header)
+				}
+				rest := header[strings.Index(header, "+"):]
+				_, rest, _ = strings.Cut(rest, " ")
+				if !regexp.MustCompile(tt.header).MatchString(regexp.MustCompile(` \S*/development_test.go:\d+ +`).ReplaceAllString(rest, " ")) {
+					t.Errorf("header %q, want a match of %s", rest, tt.header)
+				}
+
+				stackAt := strings.Index(body, "    "+stackKey)
+				if (stackAt >= 0) != tt.stack {
+					t.Fatalf("body %q, want a stack trace %v", body, tt.stack)
+				}
+				if tt.stack {
+					if !strings.Contains(body[stackAt:], "TestDevelopmentEncoder") {
+						t.Errorf("stack %q, want the stack of the logging call", body[stackAt:])
+					}
+					body = body[:stackAt]
+				}
+				if body != tt.body {
+					t.Errorf("body %q, want %q", body, tt.body)
+				}
+			})
+		}
+	}
+}
+
+func TestDevelopmentEncoderColors(t *testing.T) {
+	entry := &Entry{
+		Level:   ErrorLevel,
+		Time:    processStart.Add(1500 * time.Millisecond),
+		Message: "failed",
+		Caller:  &runtime.Frame{File: "/src/app/main.go", Line: 7},
+		Fields:  []Field{{Key: "user", Value: "ann"}},
+	}
+	tests := []struct {
+		name  string
+		color bool
+		want  string
+	}{
+		{"plain", false, "     +1.5s ERROR app/main.go:7            failed\n" +
+			"    user        ann\n" +
+			"    stacktrace  main.main\n" +
+			"                  main.go:7\n"},
+		{"color", true, "\x1b[2m     +1.5s\x1b[0m \x1b[31mERROR\x1b[0m \x1b[2mapp/main.go:7           \x1b[0m failed\n" +
+			"    \x1b[36muser      \x1b[0m  ann\n" +
+			"    \x1b[36mstacktrace\x1b[0m  \x1b[2mmain.main\x1b[0m\n" +
+			"                \x1b[2m  main.go:7\x1b[0m\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := buffer.NewPool().Get()
+			developmentEncoder(tt.color)(buf, entry, "main.main\n\tmain.go:7")
+			if got := buf.String(); got != tt.want {
+				t.Errorf("encoded\n%q, want\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+// stringer is a fmt.Stringer for tests.
+type stringer string
+
+func (s stringer) String() string {
+	return string(s)
+}
+
+func TestDevValueLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  []string
+	}{
+		{"nil", nil, []string{"<nil>"}},
+		{"empty string", "", []string{`""`}},
+		{"multiline string", "a\n\tb", []string{"a", "  b"}},
+		{"error", errors.New("outer\ninner"), []string{"outer", "inner"}},
+		{"stringer", stringer("s"), []string{"s"}},
+		{"time", time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), []string{"2024-05-01T12:00:00Z"}},
+		{"number", 1.5, []string{"1.5"}},
+		{"bool", true, []string{"true"}},
+		{"empty list", []any{}, []string{"[]"}},
+		{"list", []string{"a", "b"}, []string{"- a", "- b"}},
+		{"list of multiline items", []any{"a\nb", 1}, []string{"- a", "  b", "- 1"}},
+		{"object", map[string]int{"k": 1}, []string{"{", `  "k": 1`, "}"}},
+		{"not JSON", make(chan int), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := devValueLines(tt.value)
+			if tt.want == nil {
+				// Values JSON cannot encode are written with fmt
+				if len(got) != 1 || !strings.HasPrefix(got[0], "0x") {
+					t.Errorf("devValueLines() = %q, want the fmt form", got)
+				}
+				return
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("devValueLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDevelopmentPreset(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			builder, out := newCaptureBuilder(backend.typ, JSONEncoder)
+			logger := builder.Development().Build()
+			logger.Trace("filtered")
+			logger.Debug("debug")
+			_, line := out.last(t)
+			if out.count() != 1 || !regexp.MustCompile(`^ *\+\S+ DEBUG .* debug\n$`).MatchString(line) {
+				t.Errorf("got %d entries, last %q, want the debug entry in the development format", out.count(), line)
+			}
+
+			// Later calls override the preset
+			builder, out = newCaptureBuilder(backend.typ, JSONEncoder)
+			logger = builder.Development().LogLevel(WarnLevel).EncoderType(LogfmtEncoder).Build()
+			logger.Info("filtered")
+			logger.Warn("warn")
+			_, line = out.last(t)
+			if out.count() != 1 || !regexp.MustCompile(`^ts=\S+ level=warn .*msg=warn\n$`).MatchString(line) {
+				t.Errorf("got %d entries, last %q, want the warn entry in logfmt", out.count(), line)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package abslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.uber.org/zap/buffer"
+)
+
+// ecsVersion is the Elastic Common Schema version of the encoded documents.
+const ecsVersion = "8.11.0"
+
+// Field keys the ECS encoder reads the trace context from
+var (
+	ecsTraceFields = []string{"trace_id", "traceId", "trace"}
+	ecsSpanFields  = []string{"span_id", "spanId"}
+)
+
+// ecsReservedKeys are the keys written by the ECS encoder. Fields using them
+// are moved under labels, e.g. a message field becomes labels.message, so that
+// documents have no duplicate keys and the encoder values are kept.
+var ecsReservedKeys = map[string]bool{
+	"@timestamp": true, "log.level": true, "message": true, "ecs.version": true, "service.name": true,
+	"log.logger": true, "log.origin.file.name": true, "log.origin.file.line": true, "log.origin.function": true,
+}
+
+// ecsEncoder returns an entryEncoder writing entries as ECS documents of the given service.
+func ecsEncoder(service string) entryEncoder {
+	return func(buf *buffer.Buffer, entry *Entry, stack string) {
+		appendECSDocument(buf, service, entry, stack)
+		buf.AppendByte('\n')
+	}
+}
+
+// ecsServiceName returns the service name set in the OTEL_SERVICE_NAME or
+// ELASTIC_APM_SERVICE_NAME environment variable, or the executable name.
+func ecsServiceName() string {
+	for _, env := range []string{"OTEL_SERVICE_NAME", "ELASTIC_APM_SERVICE_NAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// appendECSDocument writes an entry as an ECS document, starting with
+// @timestamp, log.level and message as ECS logging recommends:
+//   - the caller as log.origin.file.name, log.origin.file.line and log.origin.function
+//   - the logger name as log.logger and the service as service.name
+//   - the error field expanded by abslog as error.message, error.type and
+//     error.stack_trace, which holds the stack carried by the error or, at
+//     error level and above, the stack of the logging call
+//   - the trace_id, traceId or trace field as trace.id and the span_id or
+//     spanId field as span.id, or a W3C traceparent field as both
+//   - other fields, including context values, under their own keys, or under
+//     labels for the keys written by the encoder (see ecsReservedKeys)
+func appendECSDocument(buf *buffer.Buffer, service string, entry *Entry, stack string) {
+	doc := make(map[string]any, len(entry.Fields)+8)
+	for _, f := range entry.Fields {
+		if ecsReservedKeys[f.Key] {
+			doc["labels."+f.Key] = f.Value
+			continue
+		}
+		doc[f.Key] = f.Value
+	}
+	delete(doc, nameKey)
+
+	doc["ecs.version"] = ecsVersion
+	doc["service.name"] = service
+	if entry.Logger != "" {
+		doc["log.logger"] = entry.Logger
+	}
+	if entry.Caller != nil {
+		doc["log.origin.file.name"] = filepath.Base(entry.Caller.File)
+		doc["log.origin.file.line"] = entry.Caller.Line
+		doc["log.origin.function"] = entry.Caller.Function
+	}
+
+	if message, ok := doc[defaultErrorKey]; ok {
+		delete(doc, defaultErrorKey)
+		doc["error.message"] = message
+		if errType, ok := doc[defaultErrorKey+errorTypeSuffix]; ok {
+			delete(doc, defaultErrorKey+errorTypeSuffix)
+			doc["error.type"] = errType
+		}
+	}
+	if errStack, ok := doc[defaultErrorKey+errorStackSuffix]; ok {
+		delete(doc, defaultErrorKey+errorStackSuffix)
+		doc["error.stack_trace"] = errStack
+	} else if stack != "" {
+		doc["error.stack_trace"] = stack
+	}
+
+	if traceparent, ok := doc["traceparent"].(string); ok {
+		if traceID, spanID, _, ok := parseTraceparent(traceparent); ok {
+			delete(doc, "traceparent")
+			doc["trace.id"] = traceID
+			doc["span.id"] = spanID
+		}
+	}
+	if trace := takeFirstField(doc, ecsTraceFields); trace != nil {
+		doc["trace.id"] = fmt.Sprint(trace)
+	}
+	if span := takeFirstField(doc, ecsSpanFields); span != nil {
+		doc["span.id"] = fmt.Sprint(span)
+	}
+
+	rest, err := json.Marshal(doc)
+	if err != nil {
+		// Fall back to the text of the field values that JSON cannot encode
+		for key, value := range doc {
+			if _, ok := value.(string); !ok {
+				doc[key] = fmt.Sprint(value)
+			}
+		}
+		rest, _ = json.Marshal(doc)
+	}
+
+	timestamp, _ := json.Marshal(formatEntryTime(entry.Time))
+	level, _ := json.Marshal(levelName(entry.Level))
+	message, _ := json.Marshal(entry.Message)
+	buf.AppendString(`{"@timestamp":`)
+	_, _ = buf.Write(timestamp)
+	buf.AppendString(`,"log.level":`)
+	_, _ = buf.Write(level)
+	buf.AppendString(`,"message":`)
+	_, _ = buf.Write(message)
+	buf.AppendByte(',')
+	// The other keys follow the leading ones, without their opening brace
+	_, _ = buf.Write(rest[1:])
+}
@@ -0,0 +1,121 @@
+package abslog
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// ecsKeys returns the keys of an ECS document in order, failing the test if
+// the document is not a JSON object.
+func ecsKeys(t *testing.T, line string) []string {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(line))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		t.Fatalf("line %q is not a JSON object", line)
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, tok.(string))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return keys
+}
+
+func TestECSEncoder(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "checkout")
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name string
+		log  func(logger AbsLog)
+		want map[string]any
+		// absent are keys the document must not have
+		absent []string
+	}{
+		{"info", func(l AbsLog) { l.With("user", "ann", "n", 3).Info("hello") },
+			map[string]any{"log.level": "info", "message": "hello", "user": "ann", "n": 3.0, "ecs.version": ecsVersion, "service.name": "checkout"},
+			[]string{"error.stack_trace", "log.logger", "logger"}},
+		{"logger name", func(l AbsLog) { l.Named("api").Named("db").Warn("m") },
+			map[string]any{"log.level": "warn", "log.logger": "api.db"}, []string{"logger"}},
+		{"reserved keys", func(l AbsLog) {
+			l.With("message", "field", "@timestamp", "t", "log.level", "l", "ecs.version", "v", "service.name", "s").Info("m")
+		},
+			map[string]any{"message": "m", "log.level": "info", "ecs.version": ecsVersion, "service.name": "checkout",
+				"labels.message": "field", "labels.@timestamp": "t", "labels.log.level": "l", "labels.ecs.version": "v", "labels.service.name": "s"}, nil},
+		{"trace fields", func(l AbsLog) { l.With("trace_id", "t1", "spanId", "s1").Info("m") },
+			map[string]any{"trace.id": "t1", "span.id": "s1"}, []string{"trace_id", "spanId"}},
+		{"traceparent", func(l AbsLog) { l.With("traceparent", traceparent).Info("m") },
+			map[string]any{"trace.id": "4bf92f3577b34da6a3ce929d0e0e4736", "span.id": "00f067aa0ba902b7"}, []string{"traceparent"}},
+		{"invalid traceparent", func(l AbsLog) { l.With("traceparent", "00-abc").Info("m") },
+			map[string]any{"traceparent": "00-abc"}, []string{"trace.id"}},
+		{"error", func(l AbsLog) { l.ErrorErr(errors.New("boom"), "failed") },
+			map[string]any{"log.level": "error", "error.message": "boom", "error.type": "*errors.errorString"},
+			[]string{"error", "error_type"}},
+		{"error with stack", func(l AbsLog) { l.With(Err(pkgErr{})).Warn("warned") },
+			map[string]any{"error.message": "pkg error", "error.stack_trace": "main.run\n\tmain.go:10"}, []string{"error_stack"}},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, ECSEncoder)
+				tt.log(logger)
+				_, line := out.last(t)
+
+				keys := ecsKeys(t, line)
+				if len(keys) < 3 || keys[0] != "@timestamp" || keys[1] != "log.level" || keys[2] != "message" {
+					t.Errorf("keys %v, want @timestamp, log.level and message first", keys)
+				}
+				seen := make(map[string]bool)
+				for _, key := range keys {
+					if seen[key] {
+						t.Errorf("duplicate key %q in %s", key, line)
+					}
+					seen[key] = true
+				}
+
+				var doc map[string]any
+				if err := json.Unmarshal([]byte(line), &doc); err != nil {
+					t.Fatal(err)
+				}
+				for key, want := range tt.want {
+					if doc[key] != want {
+						t.Errorf("%s = %v, want %v", key, doc[key], want)
+					}
+				}
+				for _, key := range tt.absent {
+					if _, ok := doc[key]; ok {
+						t.Errorf("%s = %v, want no such key", key, doc[key])
+					}
+				}
+				if doc["log.origin.file.name"] != "ecs_test.go" || doc["log.origin.function"] == "" {
+					t.Errorf("origin = %v %v, want the caller", doc["log.origin.file.name"], doc["log.origin.function"])
+				}
+				if stack, _ := doc["error.stack_trace"].(string); tt.name == "error" && !strings.Contains(stack, "TestECSEncoder") {
+					t.Errorf("error.stack_trace = %q, want the stack of the logging call", stack)
+				}
+			})
+		}
+	}
+}
+
+func TestECSServiceName(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "")
+	t.Setenv("ELASTIC_APM_SERVICE_NAME", "apm")
+	if got := ecsServiceName(); got != "apm" {
+		t.Errorf("ecsServiceName() = %q, want apm", got)
+	}
+	t.Setenv("ELASTIC_APM_SERVICE_NAME", "")
+	if got := ecsServiceName(); got == "" {
+		t.Error("ecsServiceName() is empty, want the executable name")
+	}
+}
@@ -0,0 +1,205 @@
+package abslog
+
+import (
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// stackKey is the key of the stack trace written with entries at error level and above.
+const stackKey = "stacktrace"
+
+// entryEncoder writes an entry in the format of an encoder type implemented by
+// abslog rather than by the backends, so that both backends produce the same
+// output. stack is the stack trace of the logging call, empty below error level.
+type entryEncoder func(buf *buffer.Buffer, entry *Entry, stack string)
+
+// encoderBuffers is the pool of buffers entries are encoded into.
+var encoderBuffers = buffer.NewPool()
+
+// encoderCtxAsFields reports whether an encoder type writes context values as
+// fields rather than as a message prefix.
+func encoderCtxAsFields(encoder EncoderType) bool {
+	switch encoder {
+	case JSONEncoder, LogfmtEncoder, GELFEncoder, GCPEncoder, ECSEncoder, DevelopmentEncoder:
+		return true
+	default:
+		return false
+	}
+}
+
+// zapEntryEncoder is a zap encoder writing entries with an entryEncoder.
+type zapEntryEncoder struct {
+	// MapObjectEncoder holds the fields added with zap's With
+	*zapcore.MapObjectEncoder
+	encode entryEncoder
+}
+
+// newZapEntryEncoder creates a zap encoder writing entries with encode.
+func newZapEntryEncoder(encode entryEncoder) zapcore.Encoder {
+	return &zapEntryEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), encode: encode}
+}
+
+// Clone returns a copy of the encoder and its fields.
+func (e *zapEntryEncoder) Clone() zapcore.Encoder {
+	clone := zapcore.NewMapObjectEncoder()
+	maps.Copy(clone.Fields, e.Fields)
+	return &zapEntryEncoder{MapObjectEncoder: clone, encode: e.encode}
+}
+
+// EncodeEntry encodes the entry with the encoder fields and the given fields.
+func (e *zapEntryEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	maps.Copy(enc.Fields, e.Fields)
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	entry := &Entry{
+		Level:   fromZapLevel(ent.Level),
+		Time:    ent.Time,
+		Message: ent.Message,
+		Fields:  make([]Field, 0, len(enc.Fields)),
+	}
+	for _, key := range slices.Sorted(maps.Keys(enc.Fields)) {
+		entry.Fields = append(entry.Fields, Field{Key: key, Value: enc.Fields[key]})
+	}
+	entry.Logger = loggerName(entry.Fields)
+	if ent.Caller.Defined {
+		entry.Caller = &runtime.Frame{PC: ent.Caller.PC, File: ent.Caller.File, Line: ent.Caller.Line, Function: ent.Caller.Function}
+	}
+
+	buf := encoderBuffers.Get()
+	e.encode(buf, entry, ent.Stack)
+	return buf, nil
+}
+
+// logrusEntryFormatter is a Logrus formatter writing entries with an entryEncoder.
+type logrusEntryFormatter struct {
+	encode entryEncoder
+}
+
+// Format encodes the entry. Entries at error level and above get the stack
+// trace of the logging call, like zap entries.
+func (f *logrusEntryFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	entry := fromLogrusEntry(e)
+	var stack string
+	if entry.Level >= ErrorLevel {
+		stack = callerStack(entry.Caller)
+	}
+
+	buf := encoderBuffers.Get()
+	defer buf.Free()
+	f.encode(buf, entry, stack)
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// fromLogrusEntry converts a Logrus entry to an Entry with fields sorted by key.
+func fromLogrusEntry(e *logrus.Entry) *Entry {
+	entry := &Entry{
+		Level:   fromLogrusEntryLevel(e),
+		Time:    e.Time,
+		Message: e.Message,
+		Fields:  make([]Field, 0, len(e.Data)),
+		Caller:  e.Caller,
+	}
+	for k, v := range e.Data {
+		entry.Fields = append(entry.Fields, Field{Key: k, Value: v})
+	}
+	sort.Slice(entry.Fields, func(i, j int) bool { return entry.Fields[i].Key < entry.Fields[j].Key })
+	entry.Logger = loggerName(entry.Fields)
+	return entry
+}
+
+// callerStack returns the current stack from the caller frame outwards, in
+// the format of zap stack traces, or "" if the caller is not on the stack.
+func callerStack(caller *runtime.Frame) string {
+	if caller == nil {
+		return ""
+	}
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+
+	var b strings.Builder
+	found := false
+	for {
+		frame, more := frames.Next()
+		found = found || (frame.File == caller.File && frame.Line == caller.Line)
+		// Like zap, leave out the goroutine entry point
+		if found && frame.Function != "runtime.goexit" {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// shortCaller returns the caller as "dir/file.go:line", like zap's ShortCallerEncoder.
+func shortCaller(caller *runtime.Frame) string {
+	return zapcore.EntryCaller{Defined: true, File: caller.File, Line: caller.Line}.TrimmedPath()
+}
+
+// takeFirstField removes the first of keys found in fields and returns its value.
+func takeFirstField(fields map[string]any, keys []string) any {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok && value != nil {
+			delete(fields, key)
+			return value
+		}
+	}
+	return nil
+}
+
+// parseTraceparent returns the trace ID, span ID and sampled flag of a W3C
+// traceparent value (version-traceid-spanid-flags). The IDs must be lowercase
+// hexadecimal and not all zeros, and the sampled flag is the lowest bit of
+// the hexadecimal flags.
+func parseTraceparent(value string) (traceID, spanID string, sampled, ok bool) {
+	parts := strings.Split(value, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", "", false, false
+	}
+	if !isTraceID(parts[1]) || !isTraceID(parts[2]) || !isLowerHex(parts[0]) || parts[0] == "ff" {
+		return "", "", false, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return "", "", false, false
+	}
+	return parts[1], parts[2], flags&1 == 1, true
+}
+
+// isTraceID reports whether s is a valid W3C trace or span ID: lowercase
+// hexadecimal, not all zeros.
+func isTraceID(s string) bool {
+	return isLowerHex(s) && strings.Trim(s, "0") != ""
+}
+
+// isLowerHex reports whether s only holds lowercase hexadecimal digits.
+func isLowerHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
+
+// encoderTimeFormat is the timestamp format of the encoders implemented by abslog.
+const encoderTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// formatEntryTime formats an entry time in UTC, using the current time when unset.
+func formatEntryTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(encoderTimeFormat)
+}
@@ -0,0 +1,157 @@
+package abslog
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Suffixes appended to an error field key to build the keys of its derived fields.
+const (
+	// errorTypeSuffix is appended to the key holding the dynamic type of the error.
+	errorTypeSuffix = "_type"
+	// errorChainSuffix is appended to the key holding the wrapped error chain.
+	errorChainSuffix = "_chain"
+	// errorStackSuffix is appended to the key holding the stack trace carried by the error.
+	errorStackSuffix = "_stack"
+)
+
+// defaultErrorKey is the key used by Err.
+const defaultErrorKey = "error"
+
+// nilErrorText is logged in place of errors holding a nil pointer.
+const nilErrorText = "<nil>"
+
+// Err returns a Field for err under the "error" key.
+// When logged, it is expanded into the error message, its type, the full
+// errors.Unwrap / errors.Join chain and any stack trace carried by the error.
+func Err(err error) Field {
+	return Field{Key: defaultErrorKey, Value: err}
+}
+
+// errorFields builds the structured fields describing err under the given key.
+func errorFields(key string, err error) []Field {
+	fields := []Field{
+		{Key: key, Value: err.Error()},
+		{Key: key + errorTypeSuffix, Value: fmt.Sprintf("%T", err)},
+	}
+
+	if chain := errorChain(err); len(chain) > 1 {
+		fields = append(fields, Field{Key: key + errorChainSuffix, Value: chain})
+	}
+
+	if stack := errorStack(err); stack != "" {
+		fields = append(fields, Field{Key: key + errorStackSuffix, Value: stack})
+	}
+
+	return fields
+}
+
+// isNilError reports whether err is nil or a nil pointer stored in an error
+// (e.g. a (*MyErr)(nil) returned as error), whose methods may panic.
+func isNilError(err error) bool {
+	if err == nil {
+		return true
+	}
+	v := reflect.ValueOf(err)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// errorChain walks the error tree depth-first, following both Unwrap() error
+// and Unwrap() []error, and returns one "type: message" entry per error.
+func errorChain(err error) []string {
+	var chain []string
+	var walk func(e error)
+	walk = func(e error) {
+		if isNilError(e) {
+			return
+		}
+		chain = append(chain, fmt.Sprintf("%T: %s", e, e.Error()))
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				walk(inner)
+			}
+		}
+	}
+	walk(err)
+	return chain
+}
+
+// errorStack returns the deepest stack trace found in the error chain.
+// It recognizes the common conventions used by error libraries:
+//   - StackTrace() of any type printable with %+v (github.com/pkg/errors)
+//   - StackTrace() []uintptr or Callers() []uintptr
+//   - Stack() []byte or Stack() string (github.com/go-errors/errors)
+func errorStack(err error) string {
+	var stack string
+	var walk func(e error)
+	walk = func(e error) {
+		if isNilError(e) {
+			return
+		}
+		if s := stackOf(e); s != "" {
+			stack = s
+		}
+		switch u := e.(type) {
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				walk(inner)
+			}
+		}
+	}
+	walk(err)
+	return stack
+}
+
+// stackOf extracts the stack trace carried directly by err, if any.
+func stackOf(err error) string {
+	switch e := err.(type) {
+	case interface{ StackTrace() []uintptr }:
+		return formatCallers(e.StackTrace())
+	case interface{ Callers() []uintptr }:
+		return formatCallers(e.Callers())
+	case interface{ Stack() []byte }:
+		return strings.TrimSpace(string(e.Stack()))
+	case interface{ Stack() string }:
+		return strings.TrimSpace(e.Stack())
+	}
+
+	// StackTrace methods returning library specific types (e.g. errors.StackTrace)
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+	trace := method.Call(nil)[0]
+	if trace.Kind() == reflect.Slice && trace.Len() == 0 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%+v", trace.Interface()))
+}
+
+// formatCallers renders program counters as "function\n\tfile:line" lines.
+func formatCallers(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		builder.WriteString(frame.Function)
+		builder.WriteString("\n\t")
+		builder.WriteString(frame.File)
+		builder.WriteString(":")
+		builder.WriteString(fmt.Sprint(frame.Line))
+		if !more {
+			break
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
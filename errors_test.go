@@ -0,0 +1,215 @@
+package abslog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// testErr is an error with a pointer receiver, to be stored as a nil pointer.
+type testErr struct {
+	msg string
+}
+
+func (e *testErr) Error() string {
+	return e.msg
+}
+
+// callersErr carries a stack trace as program counters.
+type callersErr struct {
+	pcs []uintptr
+}
+
+func (e *callersErr) Error() string {
+	return "with callers"
+}
+
+func (e *callersErr) StackTrace() []uintptr {
+	return e.pcs
+}
+
+// stackTrace mimics the library specific stack types, e.g. github.com/pkg/errors.StackTrace.
+type stackTrace []string
+
+func (s stackTrace) Format(f fmt.State, _ rune) {
+	fmt.Fprint(f, strings.Join(s, "\n"))
+}
+
+// pkgErr carries a stack trace of a library specific type.
+type pkgErr struct{}
+
+func (pkgErr) Error() string {
+	return "pkg error"
+}
+
+func (pkgErr) StackTrace() stackTrace {
+	return stackTrace{"main.run", "\tmain.go:10"}
+}
+
+// stackStringErr carries a stack trace as text.
+type stackStringErr struct{}
+
+func (stackStringErr) Error() string {
+	return "stack string"
+}
+
+func (stackStringErr) Stack() string {
+	return "  goroutine 1 [running]:\n"
+}
+
+// nilWrapErr wraps an error holding a nil pointer.
+type nilWrapErr struct{}
+
+func (nilWrapErr) Error() string {
+	return "wraps nil"
+}
+
+func (nilWrapErr) Unwrap() error {
+	return (*testErr)(nil)
+}
+
+func TestErrorErr(t *testing.T) {
+	pcs := make([]uintptr, 8)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+
+	base := errors.New("base")
+	tests := []struct {
+		name   string
+		err    error
+		fields map[string]string
+		// stack is a part of the error stack field, empty if there must be none
+		stack string
+	}{
+		{
+			name: "plain",
+			err:  base,
+			fields: map[string]string{
+				"error":      "base",
+				"error_type": "*errors.errorString",
+			},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("read config: %w", base),
+			fields: map[string]string{
+				"error":       "read config: base",
+				"error_type":  "*fmt.wrapError",
+				"error_chain": "[*fmt.wrapError: read config: base *errors.errorString: base]",
+			},
+		},
+		{
+			name: "joined",
+			err:  errors.Join(base, errors.New("other")),
+			fields: map[string]string{
+				"error":       "base\nother",
+				"error_chain": "[*errors.joinError: base\nother *errors.errorString: base *errors.errorString: other]",
+			},
+		},
+		{
+			name:   "callers",
+			err:    fmt.Errorf("wrapped: %w", &callersErr{pcs: pcs}),
+			fields: map[string]string{"error": "wrapped: with callers"},
+			stack:  "TestErrorErr",
+		},
+		{
+			name:   "library stack type",
+			err:    pkgErr{},
+			fields: map[string]string{"error": "pkg error"},
+			stack:  "main.run\n\tmain.go:10",
+		},
+		{
+			name:   "stack string",
+			err:    stackStringErr{},
+			fields: map[string]string{"error": "stack string"},
+			stack:  "goroutine 1 [running]:",
+		},
+		{
+			name:   "nil pointer",
+			err:    (*testErr)(nil),
+			fields: map[string]string{"error": "<nil>"},
+		},
+		{
+			name: "wrapped nil pointer",
+			err:  nilWrapErr{},
+			fields: map[string]string{
+				"error":      "wraps nil",
+				"error_type": "abslog.nilWrapErr",
+			},
+		},
+	}
+
+	for _, backend := range testBackends {
+		for _, encoder := range []EncoderType{ConsoleEncoder, JSONEncoder} {
+			for _, tt := range tests {
+				t.Run(backend.name+"/"+encoder.String()+"/"+tt.name, func(t *testing.T) {
+					logger, out := newCaptureLogger(backend.typ, encoder)
+					logger.ErrorErr(tt.err, "failed", "attempt", 3)
+
+					entry, line := out.last(t)
+					if entry.Level != ErrorLevel || entry.Message != "failed" {
+						t.Errorf("got %v %q, want error \"failed\"", entry.Level, entry.Message)
+					}
+					if encoder == JSONEncoder {
+						// The error stays a field of the written line on both backends
+						message, fields := decodeJSONLine(t, line)
+						if message != "failed" || fields["error"] != tt.fields["error"] {
+							t.Errorf("line %s, want message \"failed\" and error %q", line, tt.fields["error"])
+						}
+					}
+					assertField(t, entry, "attempt", "3")
+					for key, want := range tt.fields {
+						assertField(t, entry, key, want)
+					}
+
+					stack, ok := fieldText(entry, "error_stack")
+					if tt.stack == "" && ok {
+						t.Errorf("unexpected error_stack %q", stack)
+					}
+					if tt.stack != "" && !strings.Contains(stack, tt.stack) {
+						t.Errorf("error_stack = %q, want it to contain %q", stack, tt.stack)
+					}
+				})
+			}
+		}
+	}
+}
+
+// decodeJSONLine returns the message and fields of a line written by the JSON
+// encoder: top-level keys with zap, context data with Logrus.
+func decodeJSONLine(t *testing.T, line string) (string, map[string]any) {
+	t.Helper()
+	var decoded struct {
+		Message string `json:"message"`
+		Context struct {
+			Data map[string]any `json:"data"`
+		} `json:"context"`
+	}
+	fields := make(map[string]any)
+	if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", line, err)
+	}
+	_ = json.Unmarshal([]byte(line), &fields)
+	maps.Copy(fields, decoded.Context.Data)
+	return decoded.Message, fields
+}
+
+func TestErrField(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, ConsoleEncoder)
+			logger.With(Err(errors.New("boom")), "cause", &testErr{msg: "root"}).Info("done")
+
+			entry, _ := out.last(t)
+			assertField(t, entry, "error", "boom")
+			assertField(t, entry, "cause", "root")
+			assertField(t, entry, "cause_type", "*abslog.testErr")
+			if _, ok := fieldText(entry, "error_chain"); ok {
+				t.Error("unexpected error_chain for an error without wrapped errors")
+			}
+		})
+	}
+}
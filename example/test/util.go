@@ -8,12 +8,14 @@ import (
 
 func PrintFromOtherPackage() {
 	var ctx = context.Background()
-	ctxValuesMap := map[string]any{
-		"id":   "1234567",
-		"name": "John Doe",
-		"age":  30,
-	}
-	ctx = context.WithValue(ctx, abslog.GetCtxKey(), ctxValuesMap)
+	ctx = abslog.WithValues(ctx,
+		"id", "1234567",
+		"name", "John Doe",
+		"age", 30,
+	)
+
+	// Values added downstream are merged with the ones already in the context
+	ctx = abslog.WithValues(ctx, "package", "otherpackage")
 
 	abslog.DebugCtx(ctx, "Default (Zap) Debug with context from other package")
 	abslog.InfoCtx(ctx, "Default (Zap) Info with context from other package")
@@ -0,0 +1,95 @@
+package abslog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// badKey is the key used for a trailing value that has no matching key.
+const badKey = "!BADKEY"
+
+// Field is a key/value pair attached to a log entry as structured data.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Any returns a Field holding an arbitrary value under the given key.
+func Any(key string, value any) Field {
+	return Field{Key: key, Value: value}
+}
+
+// fieldsFromKV converts a list of alternating keys and values into fields.
+// Field values are taken as-is, a key without a value is reported under badKey
+// and a non-string key is converted with fmt.Sprint.
+func fieldsFromKV(kv []any) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+
+	fields := make([]Field, 0, len(kv)/2+1)
+	for i := 0; i < len(kv); i++ {
+		switch k := kv[i].(type) {
+		case Field:
+			fields = append(fields, k)
+		case string:
+			if i+1 == len(kv) {
+				fields = append(fields, Field{Key: badKey, Value: k})
+			} else {
+				fields = append(fields, Field{Key: k, Value: kv[i+1]})
+				i++
+			}
+		default:
+			if i+1 == len(kv) {
+				fields = append(fields, Field{Key: badKey, Value: k})
+			} else {
+				fields = append(fields, Field{Key: fmt.Sprint(k), Value: kv[i+1]})
+				i++
+			}
+		}
+	}
+	return fields
+}
+
+// expandFields replaces every error-valued field with the set of fields
+// describing the error (message, type, chain and stack trace). Errors holding
+// a nil pointer are logged as "<nil>".
+func expandFields(fields []Field) []Field {
+	expanded := fields[:0:0]
+	for _, f := range fields {
+		if err, ok := f.Value.(error); ok && err != nil {
+			if isNilError(err) {
+				expanded = append(expanded, Field{Key: f.Key, Value: nilErrorText})
+				continue
+			}
+			expanded = append(expanded, errorFields(f.Key, err)...)
+			continue
+		}
+		expanded = append(expanded, f)
+	}
+	return expanded
+}
+
+// fieldsToKV flattens fields into alternating keys and values.
+func fieldsToKV(fields []Field) []any {
+	kv := make([]any, 0, len(fields)*2)
+	for _, f := range fields {
+		kv = append(kv, f.Key, f.Value)
+	}
+	return kv
+}
+
+// formatFields renders fields as "key1=value1 key2=value2" for loggers
+// without structured output support.
+func formatFields(fields []Field) string {
+	var builder strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(f.Key)
+		builder.WriteString("=")
+		builder.WriteString(fmt.Sprint(f.Value))
+	}
+	return builder.String()
+}
@@ -0,0 +1,209 @@
+package abslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+)
+
+// Special fields of structured logs recognized by Cloud Logging
+const (
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanIDKey         = "logging.googleapis.com/spanId"
+	gcpTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+	gcpLabelsKey         = "logging.googleapis.com/labels"
+)
+
+// gcpReservedKeys are the keys written by the GCP encoder. Fields using them
+// are moved to logging.googleapis.com/labels, so that the encoder values are
+// kept without dropping the field values.
+var gcpReservedKeys = map[string]bool{
+	"message": true, "severity": true, "timestamp": true, gcpSourceLocationKey: true,
+	"@type": true, "stack_trace": true,
+}
+
+// gcpErrorEventType marks entries to be reported by Error Reporting.
+const gcpErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// Field keys the GCP encoder reads the trace context from
+var (
+	gcpTraceFields   = []string{"trace", "trace_id", "traceId", gcpTraceKey}
+	gcpSpanFields    = []string{"span_id", "spanId", gcpSpanIDKey}
+	gcpSampledFields = []string{"trace_sampled", "traceSampled", gcpTraceSampledKey}
+)
+
+// gcpSeverities are the Cloud Logging severities.
+var gcpSeverities = map[string]struct{}{
+	"DEFAULT": {}, "DEBUG": {}, "INFO": {}, "NOTICE": {}, "WARNING": {},
+	"ERROR": {}, "CRITICAL": {}, "ALERT": {}, "EMERGENCY": {},
+}
+
+// gcpEncoder returns an entryEncoder writing entries as Cloud Logging
+// structured logs. Trace IDs are written as full resource names when project
+// is not empty.
+func gcpEncoder(project string) entryEncoder {
+	return func(buf *buffer.Buffer, entry *Entry, stack string) {
+		_, _ = buf.Write(gcpMessage(project, entry, stack))
+		buf.AppendByte('\n')
+	}
+}
+
+// gcpProject returns the Google Cloud project the process runs in, as set in
+// the GOOGLE_CLOUD_PROJECT or GCP_PROJECT environment variable.
+func gcpProject() string {
+	if project := os.Getenv("GOOGLE_CLOUD_PROJECT"); project != "" {
+		return project
+	}
+	return os.Getenv("GCP_PROJECT")
+}
+
+// gcpMessage encodes an entry as a Cloud Logging structured log:
+//   - severity, message and timestamp
+//   - the caller as logging.googleapis.com/sourceLocation
+//   - the trace, trace_id or traceId field as logging.googleapis.com/trace,
+//     the span_id or spanId field as logging.googleapis.com/spanId and the
+//     trace_sampled field as logging.googleapis.com/trace_sampled, or the
+//     W3C traceparent field as all three
+//   - the httpRequest field as is, expected to hold a LogEntry HttpRequest object
+//   - the labels field, a map, as logging.googleapis.com/labels with text values
+//   - other fields as top-level keys of the JSON payload, or as labels for the
+//     keys written by the encoder (see gcpReservedKeys)
+//
+// Entries with a stack trace get it in stack_trace, formatted for Error Reporting.
+func gcpMessage(project string, entry *Entry, stack string) []byte {
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	message := make(map[string]any, len(entry.Fields)+5)
+	var reserved map[string]string
+	for _, f := range entry.Fields {
+		if gcpReservedKeys[f.Key] {
+			if reserved == nil {
+				reserved = make(map[string]string)
+			}
+			reserved[f.Key] = journaldFieldValue(f.Value)
+			continue
+		}
+		message[f.Key] = f.Value
+	}
+
+	if traceparent, ok := message["traceparent"].(string); ok {
+		if traceID, spanID, sampled, ok := parseTraceparent(traceparent); ok {
+			delete(message, "traceparent")
+			message[gcpTraceKey] = traceID
+			message[gcpSpanIDKey] = spanID
+			message[gcpTraceSampledKey] = sampled
+		}
+	}
+	if trace := takeFirstField(message, gcpTraceFields); trace != nil {
+		id := fmt.Sprint(trace)
+		if project != "" && !strings.HasPrefix(id, "projects/") {
+			id = "projects/" + project + "/traces/" + id
+		}
+		message[gcpTraceKey] = id
+	}
+	if span := takeFirstField(message, gcpSpanFields); span != nil {
+		message[gcpSpanIDKey] = fmt.Sprint(span)
+	}
+	if sampled := takeFirstField(message, gcpSampledFields); sampled != nil {
+		message[gcpTraceSampledKey] = sampled == true || sampled == "true"
+	}
+	var labels map[string]string
+	if value, ok := message["labels"]; ok {
+		if text, ok := gcpLabels(value); ok {
+			delete(message, "labels")
+			labels = text
+		}
+	}
+	if reserved != nil {
+		// The labels field is copied rather than changed, and wins over the moved fields
+		maps.Copy(reserved, labels)
+		labels = reserved
+	}
+	if labels != nil {
+		message[gcpLabelsKey] = labels
+	}
+
+	message["severity"] = gcpSeverity(entry.Level)
+	message["message"] = entry.Message
+	message["timestamp"] = t.UTC().Format(time.RFC3339Nano)
+	if entry.Caller != nil {
+		message[gcpSourceLocationKey] = map[string]string{
+			"file":     entry.Caller.File,
+			"line":     strconv.Itoa(entry.Caller.Line),
+			"function": entry.Caller.Function,
+		}
+	}
+	if stack != "" {
+		// Error Reporting expects the message followed by a Go panic stack trace
+		message["@type"] = gcpErrorEventType
+		message["stack_trace"] = entry.Message + "\n\ngoroutine 1 [running]:\n" + stack
+	}
+
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		// Fall back to the text of the field values that JSON cannot encode
+		for _, f := range entry.Fields {
+			if _, ok := message[f.Key]; ok && !gcpReservedKeys[f.Key] {
+				message[f.Key] = fmt.Sprint(f.Value)
+			}
+		}
+		encoded, _ = json.Marshal(message)
+	}
+	return encoded
+}
+
+// gcpLabels converts a map field to labels, which only hold text values.
+func gcpLabels(value any) (map[string]string, bool) {
+	switch v := value.(type) {
+	case map[string]string:
+		return v, true
+	case map[string]any:
+		labels := make(map[string]string, len(v))
+		for key, value := range v {
+			labels[key] = journaldFieldValue(value)
+		}
+		return labels, true
+	default:
+		return nil, false
+	}
+}
+
+// gcpSeverity returns the Cloud Logging severity of a level. Custom levels
+// named after a severity (e.g. "notice" or "critical") use it, other custom
+// levels between info and warn are NOTICE, and the rest use the severity of
+// the closest built-in level below them.
+func gcpSeverity(level LogLevel) string {
+	name := strings.ToUpper(levelName(level))
+	if _, ok := gcpSeverities[name]; ok {
+		return name
+	}
+	if level > InfoLevel && level < WarnLevel {
+		return "NOTICE"
+	}
+	switch baseLevel(level) {
+	case TraceLevel, DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARNING"
+	case ErrorLevel:
+		return "ERROR"
+	case PanicLevel:
+		return "CRITICAL"
+	case FatalLevel:
+		return "ALERT"
+	default:
+		return "DEFAULT"
+	}
+}
@@ -0,0 +1,172 @@
+package abslog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGCPEncoder(t *testing.T) {
+	registerTestLevels()
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name    string
+		project string
+		log     func(logger AbsLog)
+		want    map[string]any
+		// absent are keys the entry must not have
+		absent []string
+	}{
+		{"info", "", func(l AbsLog) { l.With("user", "ann", "n", 3).Info("hello") },
+			map[string]any{"severity": "INFO", "message": "hello", "user": "ann", "n": 3.0},
+			[]string{"@type", "stack_trace", gcpTraceKey}},
+		{"custom level", "", func(l AbsLog) { l.Log(testNoticeLevel, "m") },
+			map[string]any{"severity": "NOTICE"}, nil},
+		{"trace fields", "", func(l AbsLog) { l.With("trace_id", "t1", "spanId", "s1", "trace_sampled", true).Info("m") },
+			map[string]any{gcpTraceKey: "t1", gcpSpanIDKey: "s1", gcpTraceSampledKey: true},
+			[]string{"trace_id", "spanId", "trace_sampled"}},
+		{"trace with project", "proj", func(l AbsLog) { l.With("trace", "t1").Info("m") },
+			map[string]any{gcpTraceKey: "projects/proj/traces/t1"}, []string{"trace"}},
+		{"traceparent", "", func(l AbsLog) { l.With("traceparent", traceparent).Info("m") },
+			map[string]any{gcpTraceKey: "4bf92f3577b34da6a3ce929d0e0e4736", gcpSpanIDKey: "00f067aa0ba902b7", gcpTraceSampledKey: true},
+			[]string{"traceparent"}},
+		{"invalid traceparent", "", func(l AbsLog) { l.With("traceparent", "00-xyz-abc-01").Info("m") },
+			map[string]any{"traceparent": "00-xyz-abc-01"}, []string{gcpTraceKey}},
+		{"labels", "", func(l AbsLog) { l.With("labels", map[string]any{"team": "core", "shard": 2}).Info("m") },
+			map[string]any{gcpLabelsKey: map[string]any{"team": "core", "shard": "2"}}, []string{"labels"}},
+		{"reserved keys", "", func(l AbsLog) {
+			l.With("message", "user message", "severity", 1, "stack_trace", "s").InfoCtx(WithValues(context.Background(), "timestamp", "t1"), "m")
+		},
+			map[string]any{"message": "m", "severity": "INFO",
+				gcpLabelsKey: map[string]any{"message": "user message", "severity": "1", "stack_trace": "s", "timestamp": "t1"}},
+			[]string{"stack_trace"}},
+		{"reserved keys with labels", "", func(l AbsLog) {
+			l.With("labels", map[string]string{"team": "core"}, "@type", "user").Info("m")
+		},
+			map[string]any{gcpLabelsKey: map[string]any{"team": "core", "@type": "user"}}, []string{"@type", "labels"}},
+		{"http request", "", func(l AbsLog) { l.With("httpRequest", map[string]any{"status": 200}).Info("m") },
+			map[string]any{"httpRequest": map[string]any{"status": 200.0}}, nil},
+		{"error", "", func(l AbsLog) { l.ErrorErr(errors.New("boom"), "failed") },
+			map[string]any{"severity": "ERROR", "error": "boom", "@type": gcpErrorEventType}, nil},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				t.Setenv("GOOGLE_CLOUD_PROJECT", tt.project)
+				t.Setenv("GCP_PROJECT", "")
+				logger, out := newCaptureLogger(backend.typ, GCPEncoder)
+				tt.log(logger)
+				_, line := out.last(t)
+
+				var message map[string]any
+				if err := json.Unmarshal([]byte(line), &message); err != nil {
+					t.Fatalf("invalid line %q: %v", line, err)
+				}
+				for key, want := range tt.want {
+					if got, _ := json.Marshal(message[key]); string(got) != mustMarshal(t, want) {
+						t.Errorf("%s = %s, want %s", key, got, mustMarshal(t, want))
+					}
+				}
+				for _, key := range tt.absent {
+					if _, ok := message[key]; ok {
+						t.Errorf("%s = %v, want no such key", key, message[key])
+					}
+				}
+
+				if ts, _ := message["timestamp"].(string); !strings.HasSuffix(ts, "Z") {
+					t.Errorf("timestamp = %q, want an RFC 3339 UTC time", ts)
+				} else if _, err := time.Parse(time.RFC3339Nano, ts); err != nil {
+					t.Error(err)
+				}
+				location, _ := message[gcpSourceLocationKey].(map[string]any)
+				if file, _ := location["file"].(string); !strings.HasSuffix(file, "gcp_test.go") || location["function"] == "" {
+					t.Errorf("source location = %v, want the caller", location)
+				}
+				stack, _ := message["stack_trace"].(string)
+				if _, ok := tt.want["@type"]; ok && (!strings.HasPrefix(stack, "failed\n\ngoroutine 1 [running]:\n") || !strings.Contains(stack, "TestGCPEncoder")) {
+					t.Errorf("stack_trace = %q, want a Go panic stack trace", stack)
+				}
+			})
+		}
+	}
+}
+
+// mustMarshal returns value encoded as JSON.
+func mustMarshal(t *testing.T, value any) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name    string
+		value   string
+		sampled bool
+		ok      bool
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", false, true},
+		{"other flags", "00-" + traceID + "-" + spanID + "-03", true, true},
+		{"high flag only", "00-" + traceID + "-" + spanID + "-10", false, true},
+		{"hex flags", "00-" + traceID + "-" + spanID + "-0b", true, true},
+		{"invalid flags", "00-" + traceID + "-" + spanID + "-0z", false, false},
+		{"non-hex trace ID", "00-" + strings.Repeat("g", 32) + "-" + spanID + "-01", false, false},
+		{"uppercase trace ID", "00-" + strings.ToUpper(traceID) + "-" + spanID + "-01", false, false},
+		{"non-hex span ID", "00-" + traceID + "-00f067aa0ba902bz-01", false, false},
+		{"zero trace ID", "00-" + strings.Repeat("0", 32) + "-" + spanID + "-01", false, false},
+		{"zero span ID", "00-" + traceID + "-" + strings.Repeat("0", 16) + "-01", false, false},
+		{"invalid version", "ff-" + traceID + "-" + spanID + "-01", false, false},
+		{"short version", "0-" + traceID + "-" + spanID + "-01", false, false},
+		{"missing part", traceID + "-" + spanID + "-01", false, false},
+		{"empty", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTrace, gotSpan, sampled, ok := parseTraceparent(tt.value)
+			if ok != tt.ok || sampled != tt.sampled {
+				t.Fatalf("parseTraceparent(%q) = sampled %v, ok %v, want %v, %v", tt.value, sampled, ok, tt.sampled, tt.ok)
+			}
+			if ok && (gotTrace != traceID || gotSpan != spanID) {
+				t.Errorf("parseTraceparent(%q) = %q, %q", tt.value, gotTrace, gotSpan)
+			}
+		})
+	}
+}
+
+func TestGCPSeverity(t *testing.T) {
+	registerTestLevels()
+
+	tests := []struct {
+		level LogLevel
+		want  string
+	}{
+		{TraceLevel, "DEBUG"},
+		{DebugLevel, "DEBUG"},
+		{InfoLevel, "INFO"},
+		{testNoticeLevel, "NOTICE"},
+		{WarnLevel, "WARNING"},
+		{ErrorLevel, "ERROR"},
+		{testAuditLevel, "ERROR"},
+		{PanicLevel, "CRITICAL"},
+		{FatalLevel, "ALERT"},
+	}
+	for _, tt := range tests {
+		if got := gcpSeverity(tt.level); got != tt.want {
+			t.Errorf("gcpSeverity(%v) = %s, want %s", tt.level, got, tt.want)
+		}
+	}
+}
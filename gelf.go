@@ -0,0 +1,238 @@
+package abslog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/buffer"
+)
+
+const (
+	// gelfVersion is the GELF version of the encoded messages.
+	gelfVersion = "1.1"
+	// defaultGELFChunkSize is the default maximum size of the GELF UDP
+	// datagrams, safe for most networks.
+	defaultGELFChunkSize = 1420
+	// gelfChunkHeader is the size of a chunk header: magic bytes, message id,
+	// sequence number and sequence count.
+	gelfChunkHeader = 12
+	// gelfMaxChunks is the maximum number of chunks of a message.
+	gelfMaxChunks = 128
+)
+
+// gelfChunkMagic are the bytes starting every chunk of a chunked GELF message.
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+// gelfEncoder returns an entryEncoder writing entries as GELF 1.1 JSON
+// messages from the given host. The stack trace is appended to the full message.
+func gelfEncoder(host string) entryEncoder {
+	return func(buf *buffer.Buffer, entry *Entry, stack string) {
+		_, _ = buf.Write(gelfMessage(host, entry, stack))
+		buf.AppendByte('\n')
+	}
+}
+
+// gelfMessage encodes an entry as a GELF 1.1 message. The first line of the
+// message is the short message and the full message is only set when the
+// message spans several lines or a stack trace is given. The caller and the
+// level name, which keeps custom levels apart, are written as _file, _line and
+// _level_name. Fields become additional fields prefixed by an underscore,
+// holding numbers as is and other values as text (see gelfFieldName).
+func gelfMessage(host string, entry *Entry, stack string) []byte {
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	message := map[string]any{
+		"version":       gelfVersion,
+		"host":          host,
+		"short_message": entry.Message,
+		"timestamp":     json.Number(fmt.Sprintf("%.3f", float64(t.UnixMilli())/1000)),
+		"level":         syslogSeverity(entry.Level),
+	}
+	if short, _, multiline := strings.Cut(entry.Message, "\n"); multiline {
+		message["short_message"] = short
+		message["full_message"] = entry.Message
+	}
+	if stack != "" {
+		message["full_message"] = entry.Message + "\n" + stack
+	}
+	if entry.Caller != nil {
+		message["_file"] = shortCaller(entry.Caller)
+		message["_line"] = entry.Caller.Line
+	}
+	message["_level_name"] = levelName(entry.Level)
+	for _, f := range entry.Fields {
+		message[gelfFieldName(f.Key)] = gelfFieldValue(f.Value)
+	}
+
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		// Every value is a string or a finite number, this should not happen
+		return fmt.Appendf(nil, `{"version":%q,"host":%q,"short_message":%q}`, gelfVersion, host, entry.Message)
+	}
+	return encoded
+}
+
+// gelfReservedFields are the additional field names that fields must not
+// take: "_id", reserved by GELF, and the names of the caller and level name.
+var gelfReservedFields = map[string]bool{"_id": true, "_file": true, "_line": true, "_level_name": true}
+
+// gelfFieldName returns the additional field name of a field key: the key
+// prefixed by an underscore, with the characters other than letters, digits,
+// underscores, dots and dashes replaced by underscores. Keys taking a reserved
+// name are prefixed by "_field_" instead, e.g. the file key becomes "_field_file".
+func gelfFieldName(key string) string {
+	name := "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if gelfReservedFields[name] {
+		return "_field" + name
+	}
+	return name
+}
+
+// gelfFieldValue returns numbers as is and other values as text, since GELF
+// additional fields can only hold strings and numbers.
+func gelfFieldValue(value any) any {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return fmt.Sprint(v)
+		}
+		return v
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Sprint(v)
+		}
+		return v
+	default:
+		return journaldFieldValue(value)
+	}
+}
+
+// GELFConfig configures a GELF output.
+type GELFConfig struct {
+	// Address is the UDP address of the GELF input, e.g. "graylog:12201".
+	Address string
+	// Host is the host name written in messages.
+	// If empty, the host name reported by the kernel is used.
+	Host string
+	// ChunkSize is the maximum size of a datagram. Larger messages are split
+	// into chunks. If zero, 1420 bytes is used.
+	ChunkSize int
+	// Gzip compresses the messages before they are chunked.
+	Gzip bool
+}
+
+// gelfOutput sends entries as GELF messages over UDP.
+type gelfOutput struct {
+	config GELFConfig
+	conn   net.Conn
+}
+
+// NewGELFOutput creates an output sending entries to a GELF UDP input, such
+// as Graylog's. Entries are encoded as GELF 1.1 messages whatever the logger
+// encoder, with context values as additional fields. Messages larger than
+// ChunkSize are sent as chunked GELF, up to 128 chunks.
+func NewGELFOutput(config GELFConfig) (Output, error) {
+	if config.Address == "" {
+		return nil, errors.New("abslog: GELF output address is required")
+	}
+	if config.Host == "" {
+		config.Host, _ = os.Hostname()
+	}
+	if config.ChunkSize == 0 {
+		config.ChunkSize = defaultGELFChunkSize
+	}
+	if config.ChunkSize <= gelfChunkHeader {
+		return nil, fmt.Errorf("abslog: invalid GELF chunk size %d", config.ChunkSize)
+	}
+
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("abslog: cannot connect to GELF input: %w", err)
+	}
+	return &gelfOutput{config: config, conn: conn}, nil
+}
+
+// ctxAsFields reports that context values must be sent as additional fields.
+func (o *gelfOutput) ctxAsFields() bool {
+	return true
+}
+
+// WriteEntry sends the entry as a GELF message. The encoded line is not used.
+// Entries at error level and above get the stack trace of the logging call,
+// like the GELF encoder writes it.
+func (o *gelfOutput) WriteEntry(entry *Entry, _ []byte) error {
+	var stack string
+	if entry.Level >= ErrorLevel {
+		stack = callerStack(entry.Caller)
+	}
+	payload := gelfMessage(o.config.Host, entry, stack)
+	if o.config.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(payload)
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		payload = buf.Bytes()
+	}
+
+	if len(payload) <= o.config.ChunkSize {
+		_, err := o.conn.Write(payload)
+		return err
+	}
+	return o.writeChunks(payload)
+}
+
+// writeChunks sends a message split into chunks sharing a random message id.
+func (o *gelfOutput) writeChunks(payload []byte) error {
+	size := o.config.ChunkSize - gelfChunkHeader
+	count := (len(payload) + size - 1) / size
+	if count > gelfMaxChunks {
+		return fmt.Errorf("abslog: GELF message of %d bytes needs more than %d chunks", len(payload), gelfMaxChunks)
+	}
+
+	chunk := make([]byte, 0, o.config.ChunkSize)
+	id := rand.Uint64()
+	for seq := range count {
+		chunk = append(chunk[:0], gelfChunkMagic...)
+		chunk = binary.BigEndian.AppendUint64(chunk, id)
+		chunk = append(chunk, byte(seq), byte(count))
+		chunk = append(chunk, payload[seq*size:min((seq+1)*size, len(payload))]...)
+		if _, err := o.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sync does nothing, since messages are not buffered.
+func (o *gelfOutput) Sync() error {
+	return nil
+}
+
+// Close closes the UDP connection.
+func (o *gelfOutput) Close() error {
+	return o.conn.Close()
+}
@@ -0,0 +1,300 @@
+package abslog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gelfInput is a test GELF UDP input reassembling chunked and compressed messages.
+type gelfInput struct {
+	conn net.PacketConn
+}
+
+// startGELFInput listens on a local UDP socket.
+func startGELFInput(t *testing.T) *gelfInput {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &gelfInput{conn: conn}
+}
+
+// addr returns the address of the input.
+func (in *gelfInput) addr() string {
+	return in.conn.LocalAddr().String()
+}
+
+// read reads the next message, returning it decoded with the number of
+// datagrams it was sent in.
+func (in *gelfInput) read(t *testing.T) (map[string]any, int) {
+	t.Helper()
+	_ = in.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var payload []byte
+	var chunks [][]byte
+	datagrams := 0
+	for {
+		buf := make([]byte, 65536)
+		n, _, err := in.conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		datagrams++
+		buf = buf[:n]
+		if !bytes.HasPrefix(buf, gelfChunkMagic) {
+			payload = buf
+			break
+		}
+
+		seq, count := int(buf[10]), int(buf[11])
+		if chunks == nil {
+			chunks = make([][]byte, count)
+		} else if binary.BigEndian.Uint64(buf[2:10]) == 0 {
+			t.Fatal("chunk without message id")
+		}
+		chunks[seq] = buf[gelfChunkHeader:]
+		if datagrams == count {
+			payload = bytes.Join(chunks, nil)
+			break
+		}
+	}
+
+	if bytes.HasPrefix(payload, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if payload, err = io.ReadAll(zr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var message map[string]any
+	if err := json.Unmarshal(payload, &message); err != nil {
+		t.Fatalf("invalid GELF message %q: %v", payload, err)
+	}
+	return message, datagrams
+}
+
+// assertGELF checks the values of a decoded GELF message.
+func assertGELF(t *testing.T, message map[string]any, want map[string]any) {
+	t.Helper()
+	for key, value := range want {
+		if got, ok := message[key]; !ok || got != value {
+			t.Errorf("%s = %v, want %v in %v", key, got, value, message)
+		}
+	}
+}
+
+func TestGELFEncoder(t *testing.T) {
+	host, _ := os.Hostname()
+
+	tests := []struct {
+		name string
+		log  func(logger AbsLog)
+		want map[string]any
+		// stack reports whether the full message holds a stack trace
+		stack bool
+	}{
+		{"info", func(l AbsLog) { l.Info("hello") },
+			map[string]any{"version": "1.1", "host": host, "short_message": "hello", "level": 6.0, "_level_name": "info"}, false},
+		{"fields", func(l AbsLog) { l.With("user", "ann", "n", 3, "ok", true, "a b", "x").Warn("m") },
+			map[string]any{"level": 4.0, "_user": "ann", "_n": 3.0, "_ok": "true", "_a_b": "x"}, false},
+		{"multiline message", func(l AbsLog) { l.Info("first\nsecond") },
+			map[string]any{"short_message": "first", "full_message": "first\nsecond"}, false},
+		{"reserved field names", func(l AbsLog) { l.With("id", 1, "file", "f", "line", "l", "level_name", "n").Debug("m") },
+			map[string]any{"level": 7.0, "_level_name": "debug", "_field_id": 1.0, "_field_file": "f", "_field_line": "l", "_field_level_name": "n"}, false},
+		{"error", func(l AbsLog) { l.ErrorErr(errors.New("boom"), "failed") },
+			map[string]any{"short_message": "failed", "level": 3.0, "_error": "boom"}, true},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, GELFEncoder)
+				tt.log(logger)
+				_, line := out.last(t)
+
+				var message map[string]any
+				if err := json.Unmarshal([]byte(line), &message); err != nil || !strings.HasSuffix(line, "}\n") {
+					t.Fatalf("invalid GELF line %q: %v", line, err)
+				}
+				assertGELF(t, message, tt.want)
+				if file, _ := message["_file"].(string); !strings.Contains(file, "/gelf_test.go:") {
+					t.Errorf("_file = %q, want the caller file", file)
+				}
+				if _, ok := message["_line"].(float64); !ok {
+					t.Errorf("_line = %v, want the caller line", message["_line"])
+				}
+				if ts, _ := message["timestamp"].(float64); math.Abs(ts-float64(time.Now().Unix())) > 60 {
+					t.Errorf("timestamp = %v, want the current time in seconds", message["timestamp"])
+				}
+				full, _ := message["full_message"].(string)
+				if stack := strings.Contains(full, "TestGELFEncoder"); stack != tt.stack {
+					t.Errorf("full message %q holds a stack trace: %v, want %v", full, stack, tt.stack)
+				}
+			})
+		}
+	}
+}
+
+func TestGELFOutput(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunkSize int
+		gzip      bool
+		message   string
+		// chunked reports whether the message is sent in several datagrams
+		chunked bool
+	}{
+		{"small", 0, false, "hello", false},
+		{"gzip", 0, true, "hello", false},
+		{"chunked", 100, false, strings.Repeat("x", 500), true},
+		{"chunked gzip", 100, true, strings.Repeat("abcdefghij", 100), true},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				in := startGELFInput(t)
+				out, err := NewGELFOutput(GELFConfig{Address: in.addr(), Host: "web-1", ChunkSize: tt.chunkSize, Gzip: tt.gzip})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer out.Close()
+
+				// Whatever the encoder, entries are sent as GELF with context values as fields
+				builder, _ := newCaptureBuilder(backend.typ, ConsoleEncoder)
+				logger := builder.Output(out).Build()
+				logger.With("file", "f.txt").InfoCtx(WithValues(t.Context(), "request_id", "r1"), tt.message)
+
+				message, datagrams := in.read(t)
+				assertGELF(t, message, map[string]any{
+					"host":          "web-1",
+					"short_message": tt.message,
+					"level":         6.0,
+					"_request_id":   "r1",
+					"_field_file":   "f.txt",
+				})
+				if file, _ := message["_file"].(string); !strings.Contains(file, "/gelf_test.go:") {
+					t.Errorf("_file = %q, want the caller file", file)
+				}
+				if (datagrams > 1) != tt.chunked {
+					t.Errorf("sent in %d datagrams, want chunked %v", datagrams, tt.chunked)
+				}
+			})
+		}
+	}
+}
+
+func TestGELFOutputStack(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			in := startGELFInput(t)
+			out, err := NewGELFOutput(GELFConfig{Address: in.addr()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+
+			builder, _ := newCaptureBuilder(backend.typ, JSONEncoder)
+			logger := builder.Output(out).Build()
+			logger.Warn("warned")
+			logger.Error("failed")
+
+			message, _ := in.read(t)
+			if _, ok := message["full_message"]; ok {
+				t.Errorf("warn entry has a full message: %v", message)
+			}
+			message, _ = in.read(t)
+			full, _ := message["full_message"].(string)
+			if !strings.HasPrefix(full, "failed\n") || !strings.Contains(full, "TestGELFOutputStack") {
+				t.Errorf("full message %q, want the message and the stack trace", full)
+			}
+		})
+	}
+}
+
+func TestGELFOutputTooManyChunks(t *testing.T) {
+	in := startGELFInput(t)
+	out, err := NewGELFOutput(GELFConfig{Address: in.addr(), ChunkSize: gelfChunkHeader + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	err = out.WriteEntry(&Entry{Level: InfoLevel, Message: strings.Repeat("x", 200)}, nil)
+	if err == nil || !strings.Contains(err.Error(), "more than 128 chunks") {
+		t.Errorf("WriteEntry() = %v, want the chunk limit error", err)
+	}
+}
+
+func TestGELFOutputConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config GELFConfig
+	}{
+		{"no address", GELFConfig{}},
+		{"chunk size", GELFConfig{Address: "127.0.0.1:12201", ChunkSize: gelfChunkHeader}},
+		{"invalid address", GELFConfig{Address: "127.0.0.1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGELFOutput(tt.config); err == nil {
+				t.Error("NewGELFOutput() did not fail")
+			}
+		})
+	}
+}
+
+func TestGELFFieldName(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"user", "_user"},
+		{"user.name", "_user.name"},
+		{"a b/c", "_a_b_c"},
+		{"_private", "__private"},
+		{"id", "_field_id"},
+		{"file", "_field_file"},
+		{"line", "_field_line"},
+		{"level_name", "_field_level_name"},
+		{"level name", "_field_level_name"},
+	}
+	for _, tt := range tests {
+		if got := gelfFieldName(tt.key); got != tt.want {
+			t.Errorf("gelfFieldName(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestGELFFieldValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  any
+	}{
+		{42, 42},
+		{uint16(7), uint16(7)},
+		{1.5, 1.5},
+		{math.NaN(), "NaN"},
+		{float32(math.Inf(1)), "+Inf"},
+		{"text", "text"},
+		{true, "true"},
+		{errors.New("boom"), "boom"},
+	}
+	for _, tt := range tests {
+		if got := gelfFieldValue(tt.value); got != tt.want {
+			t.Errorf("gelfFieldValue(%v) = %#v, want %#v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/rendis/abslog/v3/grpclog
+
+go 1.25.1
+
+require (
+	github.com/rendis/abslog/v3 v3.0.0-20261019005215-6e65a41d8f80
+	google.golang.org/grpc v1.75.0
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	github.com/TV4/logrus-stackdriver-formatter v0.1.0 // indirect
+	github.com/go-stack/stack v1.8.1 // indirect
+	github.com/sirupsen/logrus v1.9.3 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	go.uber.org/zap v1.27.0 // indirect
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+)
@@ -0,0 +1,358 @@
+// Package grpclog provides gRPC server and client interceptors that log one
+// line per call through abslog and make call data available to the
+// context-aware abslog functions used downstream.
+package grpclog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rendis/abslog/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// defaultRequestIDMetadataKey is the default metadata key used to propagate the request ID
+const defaultRequestIDMetadataKey = "x-request-id"
+
+// Keys of the values stored in the call context
+const (
+	// RequestIDKey holds the request ID, read from the call metadata or generated
+	RequestIDKey = "request_id"
+	// MethodKey holds the full gRPC method name, e.g. "/pkg.Service/Method"
+	MethodKey = "grpc_method"
+	// PeerKey holds the network address of the remote peer
+	PeerKey = "peer"
+)
+
+// Keys of the fields added to call lines
+const (
+	// CodeKey holds the gRPC status code of the call
+	CodeKey = "grpc_code"
+	// LatencyKey holds the duration of the call, in milliseconds
+	LatencyKey = "latency_ms"
+	// ErrorKey holds the error returned by the call, if any
+	ErrorKey = "error"
+)
+
+// CodeToLevel returns the level of the line logged for a call ending with the given code.
+type CodeToLevel func(code codes.Code) abslog.LogLevel
+
+// InterceptorBuilder is the interface that wraps the Builder methods to create new gRPC interceptors.
+type InterceptorBuilder interface {
+	Logger(logger abslog.AbsLog) InterceptorBuilder
+	RequestIDMetadataKey(key string) InterceptorBuilder
+	RequestIDGenerator(generator func() string) InterceptorBuilder
+	CodeToLevel(fn CodeToLevel) InterceptorBuilder
+	Build() *Interceptors
+}
+
+// interceptorBuilder is a builder for creating new gRPC interceptors.
+type interceptorBuilder struct {
+	logger       abslog.AbsLog
+	requestIDKey string
+	generateID   func() string
+	codeToLevel  CodeToLevel
+}
+
+// GetInterceptorBuilder returns a new interceptor builder.
+func GetInterceptorBuilder() InterceptorBuilder {
+	return &interceptorBuilder{
+		requestIDKey: defaultRequestIDMetadataKey,
+		generateID:   newRequestID,
+		codeToLevel:  DefaultCodeToLevel,
+	}
+}
+
+// Logger sets the logger used for call lines.
+// If nil, the logger found in the call context (see abslog.FromContext) is used.
+// Call data is stored under the context key of the logger (see abslog.LoggerCtxKey).
+func (builder *interceptorBuilder) Logger(logger abslog.AbsLog) InterceptorBuilder {
+	builder.logger = logger
+	return builder
+}
+
+// RequestIDMetadataKey sets the metadata key used to read and propagate the request ID.
+// Keys are lowercased, as required by gRPC metadata.
+// If empty or only whitespace, the default key "x-request-id" will be used.
+func (builder *interceptorBuilder) RequestIDMetadataKey(key string) InterceptorBuilder {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		key = defaultRequestIDMetadataKey
+	}
+	builder.requestIDKey = key
+	return builder
+}
+
+// RequestIDGenerator sets the function generating request IDs for calls without one.
+// If nil, random 128-bit hexadecimal IDs will be generated.
+func (builder *interceptorBuilder) RequestIDGenerator(generator func() string) InterceptorBuilder {
+	if generator == nil {
+		generator = newRequestID
+	}
+	builder.generateID = generator
+	return builder
+}
+
+// CodeToLevel sets the function choosing the level of call lines.
+// If nil, DefaultCodeToLevel will be used.
+func (builder *interceptorBuilder) CodeToLevel(fn CodeToLevel) InterceptorBuilder {
+	if fn == nil {
+		fn = DefaultCodeToLevel
+	}
+	builder.codeToLevel = fn
+	return builder
+}
+
+// Build builds the interceptors.
+func (builder *interceptorBuilder) Build() *Interceptors {
+	// Copy the configuration so that later builder calls do not affect the interceptors
+	config := *builder
+	return &Interceptors{config: config}
+}
+
+// Interceptors holds the server and client interceptors sharing one configuration.
+type Interceptors struct {
+	config interceptorBuilder
+}
+
+// UnaryServerInterceptor returns a unary server interceptor using the default settings.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return GetInterceptorBuilder().Build().UnaryServer()
+}
+
+// StreamServerInterceptor returns a stream server interceptor using the default settings.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return GetInterceptorBuilder().Build().StreamServer()
+}
+
+// UnaryClientInterceptor returns a unary client interceptor using the default settings.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return GetInterceptorBuilder().Build().UnaryClient()
+}
+
+// StreamClientInterceptor returns a stream client interceptor using the default settings.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return GetInterceptorBuilder().Build().StreamClient()
+}
+
+// ServerOptions returns the server options installing the unary and stream server interceptors.
+func (i *Interceptors) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(i.UnaryServer()),
+		grpc.ChainStreamInterceptor(i.StreamServer()),
+	}
+}
+
+// DialOptions returns the dial options installing the unary and stream client interceptors.
+func (i *Interceptors) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(i.UnaryClient()),
+		grpc.WithChainStreamInterceptor(i.StreamClient()),
+	}
+}
+
+// UnaryServer returns the unary server interceptor.
+// It stores the call data in the context passed to the handler and logs the call once it returns.
+func (i *Interceptors) UnaryServer() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx = i.serverContext(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		i.logCall(ctx, "server", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServer returns the stream server interceptor.
+// It stores the call data in the stream context and logs the call once the handler returns.
+func (i *Interceptors) StreamServer() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := i.serverContext(ss.Context(), info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		i.logCall(ctx, "server", info.FullMethod, start, err)
+		return err
+	}
+}
+
+// UnaryClient returns the unary client interceptor.
+// It propagates the request ID in the outgoing metadata and logs the call once it returns.
+func (i *Interceptors) UnaryClient() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx = i.clientContext(ctx, method, cc.Target())
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		i.logCall(ctx, "client", method, start, err)
+		return err
+	}
+}
+
+// StreamClient returns the stream client interceptor.
+// It propagates the request ID in the outgoing metadata and logs the call once
+// the stream ends, that is when receiving a message returns an error or io.EOF,
+// or when the call context is done, e.g. for streams cancelled without being drained.
+func (i *Interceptors) StreamClient() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx = i.clientContext(ctx, method, cc.Target())
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			i.logCall(ctx, "client", method, start, err)
+			return nil, err
+		}
+		return newClientStream(ctx, cs, func(err error) { i.logCall(ctx, "client", method, start, err) }), nil
+	}
+}
+
+// serverContext returns ctx with the request ID, method and peer of an incoming call
+// stored as context values, and sends the request ID back in the response header.
+func (i *Interceptors) serverContext(ctx context.Context, method string) context.Context {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(i.config.requestIDKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = i.config.generateID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(i.config.requestIDKey, requestID))
+
+	kv := []any{RequestIDKey, requestID, MethodKey, method}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		kv = append(kv, PeerKey, p.Addr.String())
+	}
+	return abslog.WithValuesKey(ctx, abslog.LoggerCtxKey(i.logger(ctx)), kv...)
+}
+
+// clientContext returns ctx with the request ID, method and target of an outgoing
+// call stored as context values and the request ID added to the outgoing metadata.
+// The request ID already stored in ctx, if any, is reused.
+func (i *Interceptors) clientContext(ctx context.Context, method, target string) context.Context {
+	key := abslog.LoggerCtxKey(i.logger(ctx))
+	requestID, _ := abslog.ValuesFromKey(ctx, key)[RequestIDKey].(string)
+	if requestID == "" {
+		requestID = i.config.generateID()
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, i.config.requestIDKey, requestID)
+	return abslog.WithValuesKey(ctx, key, RequestIDKey, requestID, MethodKey, method, PeerKey, target)
+}
+
+// logger returns the logger of calls made with ctx: the configured logger or
+// the logger found in ctx. Call data is stored under the context key it reads.
+func (i *Interceptors) logger(ctx context.Context) abslog.AbsLog {
+	if i.config.logger != nil {
+		return i.config.logger
+	}
+	return abslog.FromContext(ctx)
+}
+
+// logCall logs the end of a call with its code and latency.
+func (i *Interceptors) logCall(ctx context.Context, kind, method string, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []any{
+		CodeKey, code.String(),
+		LatencyKey, float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		fields = append(fields, ErrorKey, err)
+	}
+
+	i.logger(ctx).With(fields...).LogCtxf(ctx, i.config.codeToLevel(code), "grpc %s %s %s", kind, method, code)
+}
+
+// DefaultCodeToLevel maps codes to levels: info for OK, warn for codes caused
+// by the caller (invalid arguments, missing resources, failed authentication,
+// cancellations...) and error for the rest.
+func DefaultCodeToLevel(code codes.Code) abslog.LogLevel {
+	switch code {
+	case codes.OK:
+		return abslog.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return abslog.WarnLevel
+	default:
+		return abslog.ErrorLevel
+	}
+}
+
+// newRequestID returns a random 128-bit hexadecimal request ID.
+func newRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// serverStream overrides the context of a server stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the stream context holding the call data.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientStream calls done once, when the stream ends.
+type clientStream struct {
+	grpc.ClientStream
+	done     func(err error)
+	once     sync.Once
+	finished chan struct{}
+}
+
+// newClientStream wraps cs, calling done when the stream ends or ctx is done,
+// whichever happens first.
+func newClientStream(ctx context.Context, cs grpc.ClientStream, done func(err error)) *clientStream {
+	s := &clientStream{ClientStream: cs, done: done, finished: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.finish(status.FromContextError(ctx.Err()).Err())
+		case <-s.finished:
+		}
+	}()
+	return s
+}
+
+// RecvMsg receives a message and reports the end of the stream on io.EOF or error.
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.finish(err)
+	}
+	return err
+}
+
+// Header returns the header metadata and reports the end of the stream on error.
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+	return md, err
+}
+
+// finish reports the end of the stream the first time it is called.
+// io.EOF means that the stream ended successfully.
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		close(s.finished)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		s.done(err)
+	})
+}
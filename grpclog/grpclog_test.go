@@ -0,0 +1,394 @@
+package grpclog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rendis/abslog/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testBackends are the logger types every test runs against.
+var testBackends = []struct {
+	name string
+	typ  abslog.LoggerType
+}{
+	{"zap", abslog.ZapLogger},
+	{"logrus", abslog.LogrusLogger},
+}
+
+// captureOutput is an abslog.Output recording the entries written to it.
+type captureOutput struct {
+	mu      sync.Mutex
+	entries []*abslog.Entry
+}
+
+func (o *captureOutput) WriteEntry(entry *abslog.Entry, _ []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	copied := *entry
+	copied.Fields = append([]abslog.Field(nil), entry.Fields...)
+	o.entries = append(o.entries, &copied)
+	return nil
+}
+
+func (o *captureOutput) Sync() error {
+	return nil
+}
+
+func (o *captureOutput) Close() error {
+	return nil
+}
+
+// wait returns the first n entries, failing the test if they are not written within a second.
+func (o *captureOutput) wait(t *testing.T, n int) []*abslog.Entry {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		o.mu.Lock()
+		entries := append([]*abslog.Entry(nil), o.entries...)
+		o.mu.Unlock()
+		if len(entries) >= n {
+			return entries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d entries, want %d", len(entries), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newCaptureLogger returns a JSON logger of the given type writing to a new
+// captureOutput, configured further by configure if not nil.
+func newCaptureLogger(typ abslog.LoggerType, configure func(abslog.AbsLogBuilder)) (abslog.AbsLog, *captureOutput) {
+	out := &captureOutput{}
+	builder := abslog.GetAbsLogBuilder().
+		LoggerType(typ).
+		EncoderType(abslog.JSONEncoder).
+		LogLevel(abslog.DebugLevel).
+		Output(out)
+	if configure != nil {
+		configure(builder)
+	}
+	return builder.Build(), out
+}
+
+// field returns the value of the entry field with the given key as text.
+func field(entry *abslog.Entry, key string) (string, bool) {
+	for _, f := range entry.Fields {
+		if f.Key == key {
+			return fmt.Sprint(f.Value), true
+		}
+	}
+	return "", false
+}
+
+// Methods of the test service
+const (
+	echoMethod   = "/test.Echo/Echo"
+	streamMethod = "/test.Echo/Stream"
+)
+
+// echoService is a test service echoing string values. The value "fail:<code>"
+// makes a call end with that code. Handlers log "handled" with the call context.
+type echoService struct {
+	logger abslog.AbsLog
+}
+
+// reply returns the reply to value, or the error it asks for.
+func (s *echoService) reply(ctx context.Context, value string) (*wrapperspb.StringValue, error) {
+	s.logger.InfoCtx(ctx, "handled")
+	var code uint32
+	if _, err := fmt.Sscanf(value, "fail:%d", &code); err == nil {
+		return nil, status.Error(codes.Code(code), "failed")
+	}
+	return wrapperspb.String(value), nil
+}
+
+// serviceDesc describes the test service: a unary Echo and a bidirectional Stream method.
+func (s *echoService) serviceDesc() *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "test.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Echo",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := &wrapperspb.StringValue{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req any) (any, error) {
+					return s.reply(ctx, req.(*wrapperspb.StringValue).GetValue())
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: echoMethod}, handler)
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Stream",
+			ServerStreams: true,
+			ClientStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				for {
+					in := &wrapperspb.StringValue{}
+					if err := stream.RecvMsg(in); err != nil {
+						if errors.Is(err, io.EOF) {
+							return nil
+						}
+						return err
+					}
+					out, err := s.reply(stream.Context(), in.GetValue())
+					if err != nil {
+						return err
+					}
+					if err := stream.SendMsg(out); err != nil {
+						return err
+					}
+				}
+			},
+		}},
+	}
+}
+
+// startServer serves the test service with server interceptors logging to
+// serverLogger and returns a client connection using client interceptors
+// logging to clientLogger.
+func startServer(t *testing.T, serverLogger, clientLogger abslog.AbsLog) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	service := &echoService{logger: serverLogger}
+	srv := grpc.NewServer(GetInterceptorBuilder().Logger(serverLogger).Build().ServerOptions()...)
+	srv.RegisterService(service.serviceDesc(), struct{}{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	dialOptions := append(GetInterceptorBuilder().Logger(clientLogger).Build().DialOptions(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", dialOptions...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// streamDesc is the client description of the Stream method.
+var streamDesc = &grpc.StreamDesc{StreamName: "Stream", ServerStreams: true, ClientStreams: true}
+
+func TestUnary(t *testing.T) {
+	tests := []struct {
+		value string
+		code  codes.Code
+		level abslog.LogLevel
+	}{
+		{"hello", codes.OK, abslog.InfoLevel},
+		{"fail:3", codes.InvalidArgument, abslog.WarnLevel},
+		{"fail:13", codes.Internal, abslog.ErrorLevel},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.code.String(), func(t *testing.T) {
+				serverLogger, serverOut := newCaptureLogger(backend.typ, nil)
+				clientLogger, clientOut := newCaptureLogger(backend.typ, nil)
+				conn := startServer(t, serverLogger, clientLogger)
+
+				ctx := abslog.WithValues(context.Background(), RequestIDKey, "r-1")
+				err := conn.Invoke(ctx, echoMethod, wrapperspb.String(tt.value), &wrapperspb.StringValue{})
+				if status.Code(err) != tt.code {
+					t.Fatalf("Invoke() = %v, want code %v", err, tt.code)
+				}
+
+				want := fmt.Sprintf("grpc server %s %s", echoMethod, tt.code)
+				server := serverOut.wait(t, 2)
+				assertCall(t, server[1], want, tt.level, tt.code)
+				for _, entry := range server {
+					if got, _ := field(entry, RequestIDKey); got != "r-1" {
+						t.Errorf("%q: request_id = %q, want the client request ID", entry.Message, got)
+					}
+				}
+				if got, _ := field(server[0], MethodKey); got != echoMethod {
+					t.Errorf("handler line method = %q, want %s", got, echoMethod)
+				}
+				if _, ok := field(server[0], PeerKey); !ok {
+					t.Error("handler line has no peer")
+				}
+
+				client := clientOut.wait(t, 1)
+				assertCall(t, client[0], fmt.Sprintf("grpc client %s %s", echoMethod, tt.code), tt.level, tt.code)
+				if got, _ := field(client[0], PeerKey); got != "passthrough:///bufnet" {
+					t.Errorf("client peer = %q, want the target", got)
+				}
+			})
+		}
+	}
+}
+
+func TestContextKey(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			withKey := func(b abslog.AbsLogBuilder) { b.ContextKey("svc") }
+			serverLogger, serverOut := newCaptureLogger(backend.typ, withKey)
+			clientLogger, clientOut := newCaptureLogger(backend.typ, withKey)
+			conn := startServer(t, serverLogger, clientLogger)
+
+			// The client reads the request ID under the key of its logger
+			ctx := abslog.WithValuesKey(context.Background(), "svc", RequestIDKey, "r-1")
+			if err := conn.Invoke(ctx, echoMethod, wrapperspb.String("hello"), &wrapperspb.StringValue{}); err != nil {
+				t.Fatal(err)
+			}
+
+			entries := append(serverOut.wait(t, 2), clientOut.wait(t, 1)...)
+			for _, entry := range entries {
+				if got, _ := field(entry, RequestIDKey); got != "r-1" {
+					t.Errorf("%q: request_id = %q, want r-1", entry.Message, got)
+				}
+				if got, _ := field(entry, MethodKey); got != echoMethod {
+					t.Errorf("%q: method = %q, want %s", entry.Message, got, echoMethod)
+				}
+			}
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name string
+		// run uses the stream and cancels the call context if needed
+		run   func(t *testing.T, cs grpc.ClientStream, cancel context.CancelFunc)
+		code  codes.Code
+		level abslog.LogLevel
+		// racy is set when the server may see the end of the stream or the cancellation first
+		racy bool
+	}{
+		{"drained", func(t *testing.T, cs grpc.ClientStream, _ context.CancelFunc) {
+			sendRecv(t, cs, "hello")
+			_ = cs.CloseSend()
+			if err := cs.RecvMsg(&wrapperspb.StringValue{}); !errors.Is(err, io.EOF) {
+				t.Errorf("RecvMsg() = %v, want io.EOF", err)
+			}
+		}, codes.OK, abslog.InfoLevel, false},
+		{"failed", func(t *testing.T, cs grpc.ClientStream, _ context.CancelFunc) {
+			_ = cs.SendMsg(wrapperspb.String("fail:5"))
+			if err := cs.RecvMsg(&wrapperspb.StringValue{}); status.Code(err) != codes.NotFound {
+				t.Errorf("RecvMsg() = %v, want NotFound", err)
+			}
+		}, codes.NotFound, abslog.WarnLevel, false},
+		{"cancelled", func(t *testing.T, cs grpc.ClientStream, cancel context.CancelFunc) {
+			sendRecv(t, cs, "hello")
+			cancel()
+		}, codes.Canceled, abslog.WarnLevel, false},
+		{"closed without draining", func(t *testing.T, cs grpc.ClientStream, cancel context.CancelFunc) {
+			sendRecv(t, cs, "hello")
+			_ = cs.CloseSend()
+			cancel()
+		}, codes.Canceled, abslog.WarnLevel, true},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				serverLogger, serverOut := newCaptureLogger(backend.typ, nil)
+				clientLogger, clientOut := newCaptureLogger(backend.typ, nil)
+				conn := startServer(t, serverLogger, clientLogger)
+
+				ctx, cancel := context.WithCancel(abslog.WithValues(context.Background(), RequestIDKey, "r-2"))
+				defer cancel()
+				cs, err := conn.NewStream(ctx, streamDesc, streamMethod)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.run(t, cs, cancel)
+
+				client := clientOut.wait(t, 1)
+				if len(client) != 1 {
+					t.Fatalf("got %d client lines, want 1", len(client))
+				}
+				assertCall(t, client[0], fmt.Sprintf("grpc client %s %s", streamMethod, tt.code), tt.level, tt.code)
+				if got, _ := field(client[0], RequestIDKey); got != "r-2" {
+					t.Errorf("client request_id = %q, want r-2", got)
+				}
+
+				server := serverOut.wait(t, 2)
+				if got, _ := field(server[0], RequestIDKey); got != "r-2" {
+					t.Errorf("handler line request_id = %q, want the client request ID", got)
+				}
+				if want := fmt.Sprintf("grpc server %s %s", streamMethod, tt.code); !tt.racy && server[len(server)-1].Message != want {
+					t.Errorf("server line = %q, want %q", server[len(server)-1].Message, want)
+				}
+
+				// The stream is only reported once, even when it is used after the call context is done
+				_ = cs.RecvMsg(&wrapperspb.StringValue{})
+				cancel()
+				time.Sleep(20 * time.Millisecond)
+				if got := len(clientOut.wait(t, 1)); got != 1 {
+					t.Errorf("got %d client lines, want 1", got)
+				}
+			})
+		}
+	}
+}
+
+// sendRecv sends value on cs and receives its echo.
+func sendRecv(t *testing.T, cs grpc.ClientStream, value string) {
+	t.Helper()
+	if err := cs.SendMsg(wrapperspb.String(value)); err != nil {
+		t.Fatal(err)
+	}
+	reply := &wrapperspb.StringValue{}
+	if err := cs.RecvMsg(reply); err != nil || reply.GetValue() != value {
+		t.Fatalf("RecvMsg() = %q, %v, want %q", reply.GetValue(), err, value)
+	}
+}
+
+// assertCall checks the message, level, code and error of a call line.
+func assertCall(t *testing.T, entry *abslog.Entry, msg string, level abslog.LogLevel, code codes.Code) {
+	t.Helper()
+	if entry.Message != msg || entry.Level != level {
+		t.Errorf("got %v %q, want %v %q", entry.Level, entry.Message, level, msg)
+	}
+	if got, _ := field(entry, CodeKey); got != code.String() {
+		t.Errorf("%s = %q, want %v", CodeKey, got, code)
+	}
+	if _, ok := field(entry, LatencyKey); !ok {
+		t.Errorf("missing field %q", LatencyKey)
+	}
+	if _, ok := field(entry, ErrorKey); ok != (code != codes.OK) {
+		t.Errorf("error field present = %v, want %v", ok, code != codes.OK)
+	}
+}
+
+func TestDefaultCodeToLevel(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want abslog.LogLevel
+	}{
+		{codes.OK, abslog.InfoLevel},
+		{codes.Canceled, abslog.WarnLevel},
+		{codes.NotFound, abslog.WarnLevel},
+		{codes.Unauthenticated, abslog.WarnLevel},
+		{codes.Unknown, abslog.ErrorLevel},
+		{codes.DeadlineExceeded, abslog.ErrorLevel},
+		{codes.Unavailable, abslog.ErrorLevel},
+	}
+	for _, tt := range tests {
+		if got := DefaultCodeToLevel(tt.code); got != tt.want {
+			t.Errorf("DefaultCodeToLevel(%v) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package abslog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testBackends are the built-in logger types every backend test runs against.
+var testBackends = []struct {
+	name string
+	typ  LoggerType
+}{
+	{"zap", ZapLogger},
+	{"logrus", LogrusLogger},
+}
+
+// captureOutput is an Output recording the entries and lines written to it.
+type captureOutput struct {
+	mu      sync.Mutex
+	entries []*Entry
+	lines   []string
+}
+
+// WriteEntry records a copy of the entry and the encoded line.
+func (o *captureOutput) WriteEntry(entry *Entry, line []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	copied := *entry
+	copied.Fields = append([]Field(nil), entry.Fields...)
+	o.entries = append(o.entries, &copied)
+	o.lines = append(o.lines, string(line))
+	return nil
+}
+
+// Sync does nothing.
+func (o *captureOutput) Sync() error {
+	return nil
+}
+
+// Close does nothing.
+func (o *captureOutput) Close() error {
+	return nil
+}
+
+// count returns the number of entries written.
+func (o *captureOutput) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// last returns the last entry written and its line, failing the test if there is none.
+func (o *captureOutput) last(t *testing.T) (*Entry, string) {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.entries) == 0 {
+		t.Fatal("no entry written")
+	}
+	return o.entries[len(o.entries)-1], o.lines[len(o.lines)-1]
+}
+
+// newCaptureBuilder returns a builder of a trace level logger of the given
+// type and encoder writing to a new captureOutput.
+func newCaptureBuilder(typ LoggerType, encoder EncoderType) (AbsLogBuilder, *captureOutput) {
+	out := &captureOutput{}
+	builder := GetAbsLogBuilder().
+		LoggerType(typ).
+		EncoderType(encoder).
+		LogLevel(TraceLevel).
+		Output(out)
+	return builder, out
+}
+
+// newCaptureLogger builds a trace level logger of the given type and encoder
+// writing to a new captureOutput.
+func newCaptureLogger(typ LoggerType, encoder EncoderType) (AbsLog, *captureOutput) {
+	builder, out := newCaptureBuilder(typ, encoder)
+	return builder.Build(), out
+}
+
+// fieldText returns the value of the entry field with the given key as text.
+// Values are compared as text since the backends hand fields to outputs with
+// different types, e.g. []any for zap and []string for Logrus.
+func fieldText(entry *Entry, key string) (string, bool) {
+	for _, f := range entry.Fields {
+		if f.Key == key {
+			return fmt.Sprint(f.Value), true
+		}
+	}
+	return "", false
+}
+
+// fieldKeys returns the keys of the entry fields.
+func fieldKeys(entry *Entry) []string {
+	keys := make([]string, len(entry.Fields))
+	for i, f := range entry.Fields {
+		keys[i] = f.Key
+	}
+	return keys
+}
+
+// assertField fails the test if the entry has no field key with the given text value.
+func assertField(t *testing.T, entry *Entry, key, want string) {
+	t.Helper()
+	got, ok := fieldText(entry, key)
+	if !ok {
+		t.Fatalf("field %q missing, fields: %v", key, fieldKeys(entry))
+	}
+	if got != want {
+		t.Errorf("field %q = %q, want %q", key, got, want)
+	}
+}
+
+// assertContains fails the test if s does not contain every one of parts.
+func assertContains(t *testing.T, s string, parts ...string) {
+	t.Helper()
+	for _, part := range parts {
+		if !strings.Contains(s, part) {
+			t.Errorf("%q does not contain %q", s, part)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package abslog
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Entry is a log entry as received by hooks, before it reaches the backend,
+// and by outputs, once encoded by the backend.
+type Entry struct {
+	// Level is the level the entry is logged at
+	Level LogLevel
+	// Time is when the entry was logged
+	Time time.Time
+	// Message is the log message. Hooks receive it without the context values
+	// prefix, which outputs receive when context values are not fields.
+	Message string
+	// Fields are the entry fields, including the logger name and the fields
+	// added with With. Hooks receive error-valued fields before expansion
+	// and outputs receive them expanded and redacted.
+	Fields []Field
+	// Context is the context given to the logging call, nil for calls without
+	// one and for entries received by an Output
+	Context context.Context
+	// Logger is the dot-separated name of the logger, empty if unnamed
+	Logger string
+	// Caller is the call site of the logging call, nil if unknown
+	Caller *runtime.Frame
+}
+
+// Hook is called with each entry before it reaches the backend. It may
+// change the entry level, message and fields. Returning false drops the entry.
+// Redaction is applied after hooks, so values added by hooks are redacted too.
+type Hook func(entry *Entry) bool
+
+// globalHooks holds the hooks registered with RegisterHook.
+var globalHooks = struct {
+	sync.RWMutex
+	list []Hook
+}{}
+
+// RegisterHook registers a hook run for the entries of every logger built by
+// abslog, before the hooks of the logger itself. Hooks run in registration order.
+func RegisterHook(hook Hook) {
+	if hook == nil {
+		return
+	}
+	globalHooks.Lock()
+	defer globalHooks.Unlock()
+	globalHooks.list = append(globalHooks.list, hook)
+}
+
+// activeHooks returns the global hooks followed by the hooks of the logger.
+func (a *LoggerAdapter) activeHooks() []Hook {
+	globalHooks.RLock()
+	global := globalHooks.list
+	globalHooks.RUnlock()
+
+	if len(global) == 0 {
+		return a.hooks
+	}
+	if len(a.hooks) == 0 {
+		return global
+	}
+	return append(slices.Clip(global), a.hooks...)
+}
+
+// runHooks runs hooks in order on entry and reports whether it must be logged.
+func runHooks(hooks []Hook, entry *Entry) bool {
+	for _, hook := range hooks {
+		if !hook(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// callerFrame returns the frame of the function skip frames above the caller of callerFrame.
+func callerFrame(skip int) *runtime.Frame {
+	var pcs [1]uintptr
+	// Skip runtime.Callers and callerFrame
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return nil
+	}
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	return &frame
+}
@@ -0,0 +1,174 @@
+package abslog
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withGlobalHooks registers hooks for the duration of the test.
+func withGlobalHooks(t *testing.T, hooks ...Hook) {
+	t.Helper()
+	globalHooks.Lock()
+	previous := globalHooks.list
+	globalHooks.Unlock()
+	t.Cleanup(func() {
+		globalHooks.Lock()
+		globalHooks.list = previous
+		globalHooks.Unlock()
+	})
+	for _, hook := range hooks {
+		RegisterHook(hook)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		hooks     []Hook
+		wantLevel LogLevel
+		wantMsg   string
+		// wantFields are fields the logged entry must have, nil if it is dropped
+		wantFields map[string]string
+	}{
+		{
+			name:       "unchanged",
+			hooks:      []Hook{func(*Entry) bool { return true }},
+			wantLevel:  InfoLevel,
+			wantMsg:    "msg",
+			wantFields: map[string]string{"user": "ann"},
+		},
+		{
+			name:  "dropped",
+			hooks: []Hook{func(*Entry) bool { return false }},
+		},
+		{
+			name: "changed",
+			hooks: []Hook{func(e *Entry) bool {
+				e.Level = ErrorLevel
+				e.Message = strings.ToUpper(e.Message)
+				e.Fields = append(e.Fields, Field{Key: "hooked", Value: true})
+				return true
+			}},
+			wantLevel:  ErrorLevel,
+			wantMsg:    "MSG",
+			wantFields: map[string]string{"user": "ann", "hooked": "true"},
+		},
+		{
+			name:  "lowered below the logger level",
+			hooks: []Hook{func(e *Entry) bool { e.Level = DebugLevel; return true }},
+		},
+		{
+			name: "run in order",
+			hooks: []Hook{
+				func(e *Entry) bool { e.Message += " first"; return true },
+				func(e *Entry) bool { e.Message += " second"; return true },
+			},
+			wantLevel:  InfoLevel,
+			wantMsg:    "msg first second",
+			wantFields: map[string]string{},
+		},
+		{
+			name: "stopped by a dropping hook",
+			hooks: []Hook{
+				func(*Entry) bool { return false },
+				func(*Entry) bool { panic("hook run after a dropping hook") },
+			},
+		},
+		{
+			name:       "redacted after hooks",
+			hooks:      []Hook{func(e *Entry) bool { e.Fields = append(e.Fields, Field{Key: "token", Value: "t"}); return true }},
+			wantLevel:  InfoLevel,
+			wantMsg:    "msg",
+			wantFields: map[string]string{"token": "[REDACTED]"},
+		},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				builder, out := newCaptureBuilder(backend.typ, JSONEncoder)
+				logger := builder.LogLevel(InfoLevel).RedactKeys("token").Hooks(tt.hooks...).Build()
+				logger.With("user", "ann").Info("msg")
+
+				if tt.wantFields == nil {
+					if out.count() != 0 {
+						t.Fatalf("got %d entries, want the entry dropped", out.count())
+					}
+					return
+				}
+				entry, _ := out.last(t)
+				if entry.Level != tt.wantLevel || entry.Message != tt.wantMsg {
+					t.Errorf("got %v %q, want %v %q", entry.Level, entry.Message, tt.wantLevel, tt.wantMsg)
+				}
+				for key, want := range tt.wantFields {
+					assertField(t, entry, key, want)
+				}
+			})
+		}
+	}
+}
+
+func TestHookEntry(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			var got *Entry
+			builder, _ := newCaptureBuilder(backend.typ, JSONEncoder)
+			logger := builder.Hooks(func(e *Entry) bool { got = e; return true }).Build()
+
+			ctx := WithValues(context.Background(), "request_id", "r1")
+			logger.Named("api").With("user", "ann").WarnCtx(ctx, "msg")
+
+			if got == nil {
+				t.Fatal("hook not called")
+			}
+			if got.Level != WarnLevel || got.Message != "msg" || got.Logger != "api" || got.Context != ctx {
+				t.Errorf("got level %v, message %q, logger %q, context %v", got.Level, got.Message, got.Logger, got.Context)
+			}
+			if got.Time.IsZero() {
+				t.Error("entry time not set")
+			}
+			if got.Caller == nil || filepath.Base(got.Caller.File) != "hooks_test.go" {
+				t.Errorf("caller = %+v, want hooks_test.go", got.Caller)
+			}
+			// JSON loggers write context values as fields
+			assertField(t, got, "user", "ann")
+			assertField(t, got, "request_id", "r1")
+		})
+	}
+}
+
+func TestGlobalHooks(t *testing.T) {
+	var order []string
+	withGlobalHooks(t,
+		func(e *Entry) bool { order = append(order, "global"); return true },
+		nil,
+		func(e *Entry) bool { e.Fields = append(e.Fields, Field{Key: "global", Value: 1}); return true },
+	)
+
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			order = nil
+			builder, out := newCaptureBuilder(backend.typ, JSONEncoder)
+			logger := builder.Hooks(func(e *Entry) bool { order = append(order, "logger"); return true }).Build()
+			logger.Info("msg")
+
+			if strings.Join(order, ",") != "global,logger" {
+				t.Errorf("hooks ran in order %v, want global hooks first", order)
+			}
+			entry, _ := out.last(t)
+			assertField(t, entry, "global", "1")
+
+			// Loggers without hooks of their own run the global hooks
+			order = nil
+			plain, out := newCaptureLogger(backend.typ, JSONEncoder)
+			plain.Info("msg")
+			entry, _ = out.last(t)
+			assertField(t, entry, "global", "1")
+			if len(order) != 1 {
+				t.Errorf("global hook ran %d times, want once", len(order))
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// Package httplog provides a net/http middleware that logs one access line per
+// request through abslog and makes request data available to the context-aware
+// abslog functions used downstream.
+package httplog
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rendis/abslog/v3"
+)
+
+// Default values for the middleware configuration
+const (
+	// defaultRequestIDHeader is the default header used to propagate the request ID
+	defaultRequestIDHeader = "X-Request-ID"
+	// defaultRedactionMask is the default text replacing redacted header values
+	defaultRedactionMask = "[REDACTED]"
+)
+
+// Keys of the values stored in the request context
+const (
+	// RequestIDKey holds the request ID, read from the request header or generated
+	RequestIDKey = "request_id"
+	// MethodKey holds the request method
+	MethodKey = "method"
+	// PathKey holds the request URL path
+	PathKey = "path"
+	// RemoteAddrKey holds the network address of the client
+	RemoteAddrKey = "remote_addr"
+)
+
+// Keys of the fields added to access lines
+const (
+	// StatusKey holds the response status code
+	StatusKey = "status"
+	// BytesKey holds the number of response body bytes written
+	BytesKey = "bytes"
+	// LatencyKey holds the time spent serving the request, in milliseconds
+	LatencyKey = "latency_ms"
+	// HeadersKey holds the request headers, when enabled with LogHeaders
+	HeadersKey = "headers"
+	// HijackedKey holds true for requests whose connection was hijacked, e.g.
+	// for websocket upgrades. Their status and bytes only cover what was
+	// written before the connection was taken over.
+	HijackedKey = "hijacked"
+)
+
+// defaultRedactedHeaders are the headers redacted when none are configured.
+var defaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// MiddlewareBuilder is the interface that wraps the Builder methods to create a new request-logging middleware.
+type MiddlewareBuilder interface {
+	Logger(logger abslog.AbsLog) MiddlewareBuilder
+	RequestIDHeader(header string) MiddlewareBuilder
+	RequestIDGenerator(generator func() string) MiddlewareBuilder
+	LogHeaders(enabled bool) MiddlewareBuilder
+	RedactHeaders(headers ...string) MiddlewareBuilder
+	RedactMask(mask string) MiddlewareBuilder
+	Build() func(next http.Handler) http.Handler
+}
+
+// middlewareBuilder is a builder for creating a new request-logging middleware.
+type middlewareBuilder struct {
+	logger          abslog.AbsLog
+	requestIDHeader string
+	generateID      func() string
+	logHeaders      bool
+	redactHeaders   map[string]struct{}
+	redactMask      string
+}
+
+// GetMiddlewareBuilder returns a new middleware builder.
+func GetMiddlewareBuilder() MiddlewareBuilder {
+	builder := &middlewareBuilder{
+		requestIDHeader: defaultRequestIDHeader,
+		generateID:      newRequestID,
+		redactHeaders:   make(map[string]struct{}),
+		redactMask:      defaultRedactionMask,
+	}
+	builder.RedactHeaders(defaultRedactedHeaders...)
+	return builder
+}
+
+// Middleware wraps next with a request-logging middleware using the default settings.
+func Middleware(next http.Handler) http.Handler {
+	return GetMiddlewareBuilder().Build()(next)
+}
+
+// Logger sets the logger used for access lines.
+// If nil, the logger found in the request context (see abslog.FromContext) is used.
+// Request values are stored under the context key of the logger (see abslog.LoggerCtxKey).
+func (builder *middlewareBuilder) Logger(logger abslog.AbsLog) MiddlewareBuilder {
+	builder.logger = logger
+	return builder
+}
+
+// RequestIDHeader sets the header used to read and propagate the request ID.
+// If empty or only whitespace, the default header "X-Request-ID" will be used.
+func (builder *middlewareBuilder) RequestIDHeader(header string) MiddlewareBuilder {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		header = defaultRequestIDHeader
+	}
+	builder.requestIDHeader = header
+	return builder
+}
+
+// RequestIDGenerator sets the function generating request IDs for requests without one.
+// If nil, random 128-bit hexadecimal IDs will be generated.
+func (builder *middlewareBuilder) RequestIDGenerator(generator func() string) MiddlewareBuilder {
+	if generator == nil {
+		generator = newRequestID
+	}
+	builder.generateID = generator
+	return builder
+}
+
+// LogHeaders sets whether the request headers are included in access lines.
+func (builder *middlewareBuilder) LogHeaders(enabled bool) MiddlewareBuilder {
+	builder.logHeaders = enabled
+	return builder
+}
+
+// RedactHeaders replaces the set of headers whose values are masked in access lines.
+// Header names are compared case-insensitively. By default Authorization,
+// Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key are redacted.
+func (builder *middlewareBuilder) RedactHeaders(headers ...string) MiddlewareBuilder {
+	builder.redactHeaders = make(map[string]struct{}, len(headers))
+	for _, h := range headers {
+		builder.redactHeaders[http.CanonicalHeaderKey(strings.TrimSpace(h))] = struct{}{}
+	}
+	return builder
+}
+
+// RedactMask sets the text that replaces redacted header values.
+// If mask is empty, the default mask "[REDACTED]" will be used.
+func (builder *middlewareBuilder) RedactMask(mask string) MiddlewareBuilder {
+	if mask == "" {
+		mask = defaultRedactionMask
+	}
+	builder.redactMask = mask
+	return builder
+}
+
+// Build builds the middleware.
+func (builder *middlewareBuilder) Build() func(next http.Handler) http.Handler {
+	// Copy the configuration so that later builder calls do not affect the middleware
+	m := *builder
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.serve(next, w, r)
+		})
+	}
+}
+
+// serve assigns or propagates the request ID, stores the request data in the
+// context, calls next and logs the access line.
+func (builder *middlewareBuilder) serve(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	requestID := r.Header.Get(builder.requestIDHeader)
+	if requestID == "" {
+		requestID = builder.generateID()
+	}
+	w.Header().Set(builder.requestIDHeader, requestID)
+
+	logger := builder.logger
+	if logger == nil {
+		logger = abslog.FromContext(r.Context())
+	}
+
+	// Values are stored under the key the logger reads them from
+	ctx := abslog.WithValuesKey(r.Context(), abslog.LoggerCtxKey(logger),
+		RequestIDKey, requestID,
+		MethodKey, r.Method,
+		PathKey, r.URL.Path,
+		RemoteAddrKey, r.RemoteAddr,
+	)
+	r = r.WithContext(ctx)
+
+	rw := &responseWriter{ResponseWriter: w}
+	next.ServeHTTP(rw, r)
+
+	fields := []any{
+		StatusKey, rw.statusCode(),
+		BytesKey, rw.bytes,
+		LatencyKey, float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if builder.logHeaders {
+		fields = append(fields, HeadersKey, builder.headers(r.Header))
+	}
+	if rw.hijacked {
+		fields = append(fields, HijackedKey, true)
+	}
+
+	logger.With(fields...).LogCtxf(ctx, statusLevel(rw.statusCode()), "%s %s %d", r.Method, r.URL.Path, rw.statusCode())
+}
+
+// headers returns the request headers with the configured headers redacted.
+func (builder *middlewareBuilder) headers(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if _, ok := builder.redactHeaders[http.CanonicalHeaderKey(name)]; ok {
+			headers[name] = builder.redactMask
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// statusLevel returns the level of the access line for a status code:
+// error for 5xx, warn for 4xx and info otherwise.
+func statusLevel(status int) abslog.LogLevel {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return abslog.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return abslog.WarnLevel
+	default:
+		return abslog.InfoLevel
+	}
+}
+
+// newRequestID returns a random 128-bit hexadecimal request ID.
+func newRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// responseWriter records the status code, the number of bytes written and
+// whether the connection was hijacked.
+type responseWriter struct {
+	http.ResponseWriter
+	status   int
+	bytes    int
+	hijacked bool
+}
+
+// WriteHeader records the status code and sends it to the wrapped writer.
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written to the wrapped writer.
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytes += n
+	return n, err
+}
+
+// Flush sends any buffered data to the client if the wrapped writer supports it.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection if the wrapped writer supports it, e.g. for
+// websocket upgrades, and returns http.ErrNotSupported otherwise.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil {
+		rw.hijacked = true
+	}
+	return conn, brw, err
+}
+
+// Unwrap returns the wrapped writer, for use by http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// statusCode returns the recorded status code, 200 if none was written.
+func (rw *responseWriter) statusCode() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
@@ -0,0 +1,351 @@
+package httplog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/rendis/abslog/v3"
+)
+
+// testBackends are the logger types every test runs against.
+var testBackends = []struct {
+	name string
+	typ  abslog.LoggerType
+}{
+	{"zap", abslog.ZapLogger},
+	{"logrus", abslog.LogrusLogger},
+}
+
+// captureOutput is an abslog.Output recording the entries written to it.
+type captureOutput struct {
+	mu      sync.Mutex
+	entries []*abslog.Entry
+}
+
+func (o *captureOutput) WriteEntry(entry *abslog.Entry, _ []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	copied := *entry
+	copied.Fields = append([]abslog.Field(nil), entry.Fields...)
+	o.entries = append(o.entries, &copied)
+	return nil
+}
+
+func (o *captureOutput) Sync() error {
+	return nil
+}
+
+func (o *captureOutput) Close() error {
+	return nil
+}
+
+// all returns the entries written so far.
+func (o *captureOutput) all() []*abslog.Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*abslog.Entry(nil), o.entries...)
+}
+
+// newCaptureLogger returns a JSON logger of the given type writing to a new
+// captureOutput, configured further by configure if not nil.
+func newCaptureLogger(typ abslog.LoggerType, configure func(abslog.AbsLogBuilder)) (abslog.AbsLog, *captureOutput) {
+	out := &captureOutput{}
+	builder := abslog.GetAbsLogBuilder().
+		LoggerType(typ).
+		EncoderType(abslog.JSONEncoder).
+		LogLevel(abslog.DebugLevel).
+		Output(out)
+	if configure != nil {
+		configure(builder)
+	}
+	return builder.Build(), out
+}
+
+// field returns the value of the entry field with the given key as text.
+func field(entry *abslog.Entry, key string) (string, bool) {
+	for _, f := range entry.Fields {
+		if f.Key == key {
+			return fmt.Sprint(f.Value), true
+		}
+	}
+	return "", false
+}
+
+func TestContextKey(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, func(b abslog.AbsLogBuilder) { b.ContextKey("svc") })
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := abslog.ValuesFromKey(r.Context(), "svc")[RequestIDKey]; got != "r-1" {
+					t.Errorf("request ID under the logger key = %v, want r-1", got)
+				}
+				logger.InfoCtx(r.Context(), "handling")
+			})
+			mw := GetMiddlewareBuilder().Logger(logger).Build()
+
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set("X-Request-ID", "r-1")
+			mw(handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			entries := out.all()
+			if len(entries) != 2 {
+				t.Fatalf("got %d entries, want 2", len(entries))
+			}
+			for _, entry := range entries {
+				if got, _ := field(entry, RequestIDKey); got != "r-1" {
+					t.Errorf("%q: request_id = %q, want r-1", entry.Message, got)
+				}
+				if got, _ := field(entry, PathKey); got != "/users" {
+					t.Errorf("%q: path = %q, want /users", entry.Message, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		bytes   int
+		level   abslog.LogLevel
+	}{
+		{"implicit ok", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("hello")) }, 200, 5, abslog.InfoLevel},
+		{"no body", func(http.ResponseWriter, *http.Request) {}, 200, 0, abslog.InfoLevel},
+		{"not found", func(w http.ResponseWriter, _ *http.Request) { http.Error(w, "missing", http.StatusNotFound) }, 404, 8, abslog.WarnLevel},
+		{"server error", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusBadGateway) }, 502, 0, abslog.ErrorLevel},
+		{"first status wins", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			w.WriteHeader(http.StatusInternalServerError)
+		}, 201, 0, abslog.InfoLevel},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, nil)
+				mw := GetMiddlewareBuilder().Logger(logger).Build()
+				mw(tt.handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/items", nil))
+
+				entries := out.all()
+				if len(entries) != 1 {
+					t.Fatalf("got %d entries, want 1", len(entries))
+				}
+				entry := entries[0]
+				if want := fmt.Sprintf("POST /items %d", tt.status); entry.Message != want || entry.Level != tt.level {
+					t.Errorf("got %v %q, want %v %q", entry.Level, entry.Message, tt.level, want)
+				}
+				if got, _ := field(entry, StatusKey); got != fmt.Sprint(tt.status) {
+					t.Errorf("status = %s, want %d", got, tt.status)
+				}
+				if got, _ := field(entry, BytesKey); got != fmt.Sprint(tt.bytes) {
+					t.Errorf("bytes = %s, want %d", got, tt.bytes)
+				}
+				for _, key := range []string{LatencyKey, MethodKey, RemoteAddrKey, RequestIDKey} {
+					if _, ok := field(entry, key); !ok {
+						t.Errorf("missing field %q", key)
+					}
+				}
+				for _, key := range []string{HeadersKey, HijackedKey} {
+					if _, ok := field(entry, key); ok {
+						t.Errorf("unexpected field %q", key)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(MiddlewareBuilder)
+		header    string
+		incoming  string
+		want      string
+	}{
+		{"propagated", nil, "X-Request-ID", "abc", "abc"},
+		{"generated", func(b MiddlewareBuilder) { b.RequestIDGenerator(func() string { return "gen-1" }) }, "X-Request-ID", "", "gen-1"},
+		{"custom header", func(b MiddlewareBuilder) { b.RequestIDHeader(" X-Correlation-ID ") }, "X-Correlation-ID", "corr", "corr"},
+		{"blank header uses default", func(b MiddlewareBuilder) { b.RequestIDHeader(" ") }, "X-Request-ID", "def", "def"},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, nil)
+				builder := GetMiddlewareBuilder().Logger(logger)
+				if tt.configure != nil {
+					tt.configure(builder)
+				}
+				var seen any
+				handler := http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					seen = abslog.ValuesFrom(r.Context())[RequestIDKey]
+				})
+
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				if tt.incoming != "" {
+					req.Header.Set(tt.header, tt.incoming)
+				}
+				rec := httptest.NewRecorder()
+				builder.Build()(handler).ServeHTTP(rec, req)
+
+				if got := rec.Header().Get(tt.header); got != tt.want {
+					t.Errorf("response header %s = %q, want %q", tt.header, got, tt.want)
+				}
+				if seen != tt.want {
+					t.Errorf("request ID in the handler context = %v, want %q", seen, tt.want)
+				}
+				if got, _ := field(out.all()[0], RequestIDKey); got != tt.want {
+					t.Errorf("logged request_id = %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+
+	if id := newRequestID(); len(id) != 32 || id == newRequestID() {
+		t.Errorf("newRequestID() = %q, want distinct 32-character IDs", id)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(MiddlewareBuilder)
+		want      map[string]string
+	}{
+		{"default redaction", nil, map[string]string{
+			"Authorization": "[REDACTED]", "Cookie": "[REDACTED]", "Accept": "a, b", "X-Session-Token": "s",
+		}},
+		{"custom headers and mask", func(b MiddlewareBuilder) { b.RedactHeaders("x-session-token").RedactMask("***") }, map[string]string{
+			"Authorization": "Bearer t", "Cookie": "c=1", "Accept": "a, b", "X-Session-Token": "***",
+		}},
+		{"empty mask uses default", func(b MiddlewareBuilder) { b.RedactMask("") }, map[string]string{
+			"Authorization": "[REDACTED]",
+		}},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, nil)
+				builder := GetMiddlewareBuilder().Logger(logger).LogHeaders(true)
+				if tt.configure != nil {
+					tt.configure(builder)
+				}
+
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Authorization", "Bearer t")
+				req.Header.Set("Cookie", "c=1")
+				req.Header.Add("Accept", "a")
+				req.Header.Add("Accept", "b")
+				req.Header.Set("X-Session-Token", "s")
+				builder.Build()(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(httptest.NewRecorder(), req)
+
+				var headers map[string]string
+				for _, f := range out.all()[0].Fields {
+					if f.Key == HeadersKey {
+						headers, _ = f.Value.(map[string]string)
+					}
+				}
+				if headers == nil {
+					t.Fatal("missing headers field")
+				}
+				for name, want := range tt.want {
+					if headers[name] != want {
+						t.Errorf("header %s = %q, want %q", name, headers[name], want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFlush(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, nil)
+			handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if err := http.NewResponseController(w).Flush(); err != nil {
+					t.Errorf("Flush() = %v", err)
+				}
+			})
+			rec := httptest.NewRecorder()
+			GetMiddlewareBuilder().Logger(logger).Build()(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if !rec.Flushed {
+				t.Error("the wrapped writer was not flushed")
+			}
+			if got, _ := field(out.all()[0], StatusKey); got != "200" {
+				t.Errorf("status = %s, want 200", got)
+			}
+		})
+	}
+}
+
+func TestHijack(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, nil)
+			mw := GetMiddlewareBuilder().Logger(logger).Build()
+			handler := mw(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				conn, brw, err := http.NewResponseController(w).Hijack()
+				if err != nil {
+					t.Errorf("Hijack() = %v", err)
+					return
+				}
+				defer conn.Close()
+				_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+				_ = brw.Flush()
+			}))
+
+			// served is closed once the access line is logged
+			served := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer close(served)
+				handler.ServeHTTP(w, r)
+			}))
+			defer srv.Close()
+
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			_, _ = fmt.Fprint(conn, "GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n")
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusSwitchingProtocols {
+				t.Errorf("response status = %d, want 101", resp.StatusCode)
+			}
+			<-served
+
+			entries := out.all()
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			if got, ok := field(entries[0], HijackedKey); !ok || got != "true" {
+				t.Errorf("hijacked = %q, %v, want true", got, ok)
+			}
+		})
+	}
+}
+
+func TestHijackNotSupported(t *testing.T) {
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder()}
+	if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("Hijack() = %v, want http.ErrNotSupported", err)
+	}
+	if rw.hijacked {
+		t.Error("request recorded as hijacked after a failed Hijack")
+	}
+}
@@ -0,0 +1,514 @@
+package abslog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// HTTPBatchFormat represents the body format of the batches sent by an HTTP output.
+type HTTPBatchFormat int8
+
+// HTTP batch format constants.
+const (
+	// NDJSONBatch sends one JSON entry per line (application/x-ndjson), used by default.
+	NDJSONBatch HTTPBatchFormat = iota + 1
+	// JSONArrayBatch sends a JSON array of entries (application/json).
+	JSONArrayBatch
+)
+
+// Default values for the HTTP output configuration
+const (
+	defaultHTTPBatchSize  = 100
+	defaultHTTPBatchAge   = time.Second
+	defaultHTTPQueueSize  = 10000
+	defaultHTTPMaxRetries = 5
+	defaultHTTPMinBackoff = 100 * time.Millisecond
+	defaultHTTPMaxBackoff = 10 * time.Second
+	defaultHTTPTimeout    = 10 * time.Second
+)
+
+// errOutputClosed is returned when writing to a closed output.
+var errOutputClosed = errors.New("abslog: output closed")
+
+// HTTPOutputConfig configures an HTTP output.
+type HTTPOutputConfig struct {
+	// URL is the collector endpoint the batches are POSTed to.
+	URL string
+	// Format is the batch body format. If zero, NDJSONBatch is used.
+	Format HTTPBatchFormat
+	// Gzip compresses the batch bodies.
+	Gzip bool
+	// Headers are added to every request, e.g. for authentication.
+	Headers http.Header
+	// MaxBatchSize is the maximum number of entries per batch. If zero, 100 is used.
+	MaxBatchSize int
+	// MaxBatchAge is the maximum time an entry waits for its batch to be sent.
+	// If zero, one second is used.
+	MaxBatchAge time.Duration
+	// QueueSize is the maximum number of entries waiting to be batched.
+	// Entries logged while the queue is full are dropped and counted in the
+	// "dropped" metrics (see DropRecorder). If zero, 10000 is used.
+	QueueSize int
+	// MaxRetries is the number of times a failed batch is sent again.
+	// If zero, 5 is used; if negative, failed batches are not retried.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential delay between retries.
+	// If zero, 100 milliseconds and 10 seconds are used.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Client is the HTTP client used to send batches.
+	// If nil, a client with a 10 seconds timeout is used.
+	Client *http.Client
+	// OnError is called when a batch is dropped after its last attempt.
+	// If nil, the error is written to stderr. Dropped entries are also counted
+	// in the "dropped" metrics.
+	OnError func(err error)
+	// Spool keeps the batches that fail after their last retry on disk,
+	// instead of dropping them, until the collector accepts them again.
+	// If nil, failed batches are dropped.
+	Spool *SpoolConfig
+}
+
+// httpOutput batches entries and POSTs them to a collector.
+type httpOutput struct {
+	config HTTPOutputConfig
+	// spool is nil when no spool is configured
+	spool *spool
+
+	queue   chan []byte
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	// mu guards closed and sends to queue against Close
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewHTTPOutput creates an output shipping entries to an HTTP collector.
+// Entries are queued and sent in batches by a background goroutine, when the
+// batch is full or its oldest entry reaches MaxBatchAge, and on Sync and Close.
+// Entries encoded by the JSON encoder are sent as they are, others are sent as
+// JSON objects with timestamp, severity, message, caller and fields.
+// Batches failing with a network error, a 429 or a 5xx status are retried
+// with exponential backoff, other failures drop the batch. Entries keep being
+// queued and batched while a batch waits for its retry. With a spool, the
+// batches failing after their last retry are spooled and sent again every
+// RetryInterval, before any newer batch.
+func NewHTTPOutput(config HTTPOutputConfig) (Output, error) {
+	if config.URL == "" {
+		return nil, errors.New("abslog: HTTP output URL is required")
+	}
+	if config.Format == 0 {
+		config.Format = NDJSONBatch
+	}
+	if config.Format != NDJSONBatch && config.Format != JSONArrayBatch {
+		return nil, fmt.Errorf("abslog: invalid HTTP batch format %d", config.Format)
+	}
+	if config.MaxBatchSize <= 0 {
+		config.MaxBatchSize = defaultHTTPBatchSize
+	}
+	if config.MaxBatchAge <= 0 {
+		config.MaxBatchAge = defaultHTTPBatchAge
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultHTTPQueueSize
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultHTTPMaxRetries
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultHTTPMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultHTTPMaxBackoff
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	if config.OnError == nil {
+		config.OnError = func(err error) {
+			fmt.Fprintf(os.Stderr, "abslog: HTTP output: %v\n", err)
+		}
+	}
+
+	o := &httpOutput{
+		config:  config,
+		queue:   make(chan []byte, config.QueueSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if config.Spool != nil {
+		s, err := openSpool(*config.Spool)
+		if err != nil {
+			return nil, err
+		}
+		o.spool = s
+	}
+	go o.run()
+	return o, nil
+}
+
+// WriteEntry queues the entry, or drops and counts it if the queue is full.
+func (o *httpOutput) WriteEntry(entry *Entry, line []byte) error {
+	encoded, err := jsonEntry(entry, line)
+	if err != nil {
+		return err
+	}
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if o.closed {
+		return errOutputClosed
+	}
+	select {
+	case o.queue <- encoded:
+	default:
+		recordDropped("http", 1)
+	}
+	return nil
+}
+
+// Sync sends the queued entries and waits for the batch to be sent, spooled or dropped.
+func (o *httpOutput) Sync() error {
+	flushed := make(chan struct{})
+	select {
+	case o.flushes <- flushed:
+		<-flushed
+	case <-o.stopped:
+	}
+	if o.spool != nil {
+		return o.spool.sync()
+	}
+	return nil
+}
+
+// Close sends the queued entries and stops the background goroutine.
+// Later writes fail.
+func (o *httpOutput) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	close(o.done)
+	o.mu.Unlock()
+
+	<-o.stopped
+	if o.spool != nil {
+		return o.spool.close()
+	}
+	return nil
+}
+
+// run batches the queued entries and sends them until the output is closed.
+// While a batch waits for its retry, new entries keep filling the next batch;
+// once it is full, entries wait in the queue.
+func (o *httpOutput) run() {
+	defer close(o.stopped)
+
+	batch := make([][]byte, 0, o.config.MaxBatchSize)
+	age := time.NewTimer(o.config.MaxBatchAge)
+	age.Stop()
+
+	// retrying is the batch waiting for retryTimer, nil if none
+	var retrying *retryBatch
+	retryTimer := time.NewTimer(0)
+	retryTimer.Stop()
+
+	// replayTick stays nil, and never fires, without a spool
+	var replayTick <-chan time.Time
+	if o.spool != nil {
+		ticker := time.NewTicker(o.spool.config.RetryInterval)
+		defer ticker.Stop()
+		replayTick = ticker.C
+		o.replay()
+	}
+
+	send := func() {
+		age.Stop()
+		if len(batch) == 0 {
+			return
+		}
+		if retrying = o.send(batch); retrying != nil {
+			retryTimer.Reset(retrying.delay(o.config.MaxBackoff))
+		}
+		batch = make([][]byte, 0, o.config.MaxBatchSize)
+	}
+	// flush sends the retried batch, then the batch and the queued entries,
+	// waiting for their retries
+	flush := func() {
+		retryTimer.Stop()
+		o.complete(retrying)
+		retrying = nil
+		for {
+			select {
+			case encoded := <-o.queue:
+				batch = append(batch, encoded)
+				if len(batch) >= o.config.MaxBatchSize {
+					send()
+					o.complete(retrying)
+					retrying = nil
+				}
+			default:
+				send()
+				o.complete(retrying)
+				retrying = nil
+				return
+			}
+		}
+	}
+
+	for {
+		queue := o.queue
+		if retrying != nil && len(batch) >= o.config.MaxBatchSize {
+			queue = nil
+		}
+		var retry <-chan time.Time
+		if retrying != nil {
+			retry = retryTimer.C
+		}
+
+		select {
+		case encoded := <-queue:
+			if len(batch) == 0 {
+				age.Reset(o.config.MaxBatchAge)
+			}
+			batch = append(batch, encoded)
+			if len(batch) >= o.config.MaxBatchSize && retrying == nil {
+				send()
+			}
+		case <-age.C:
+			// With a batch being retried, the batch is sent after it
+			if retrying == nil {
+				send()
+			}
+		case <-retry:
+			if retrying = o.attempt(retrying); retrying != nil {
+				retryTimer.Reset(retrying.delay(o.config.MaxBackoff))
+			} else {
+				send()
+			}
+		case <-replayTick:
+			if retrying == nil {
+				o.replay()
+			}
+		case flushed := <-o.flushes:
+			flush()
+			close(flushed)
+		case <-o.done:
+			flush()
+			return
+		}
+	}
+}
+
+// retryBatch is a batch that failed with a retryable error.
+type retryBatch struct {
+	entries [][]byte
+	body    []byte
+	// attempts is the number of failed attempts
+	attempts int
+	backoff  time.Duration
+}
+
+// delay returns the time to wait before the next attempt and doubles the
+// backoff, up to maxBackoff. Full jitter keeps clients from retrying in lockstep.
+func (r *retryBatch) delay(maxBackoff time.Duration) time.Duration {
+	d := rand.N(r.backoff) + 1
+	r.backoff = min(r.backoff*2, maxBackoff)
+	return d
+}
+
+// send sends a batch once, returning it when it must be retried.
+// While the spool holds batches, they are sent first and the batch is spooled
+// behind them if they still fail.
+func (o *httpOutput) send(batch [][]byte) *retryBatch {
+	if o.spool != nil && !o.spool.empty() {
+		if !o.replay() {
+			o.spoolBatch(batch, errors.New("collector unavailable"))
+			return nil
+		}
+	}
+
+	body, err := o.body(batch)
+	if err != nil {
+		o.drop(len(batch), fmt.Errorf("dropping %d entries: %w", len(batch), err))
+		return nil
+	}
+	return o.attempt(&retryBatch{entries: batch, body: body, backoff: o.config.MinBackoff})
+}
+
+// attempt posts a batch, returning it when it failed with a retryable error
+// and has retries left. Batches out of retries are spooled or dropped.
+func (o *httpOutput) attempt(r *retryBatch) *retryBatch {
+	retry, err := o.post(r.body)
+	switch {
+	case err == nil:
+		return nil
+	case !retry:
+		o.drop(len(r.entries), fmt.Errorf("dropping %d entries: %w", len(r.entries), err))
+		return nil
+	case r.attempts >= o.config.MaxRetries:
+		o.spoolBatch(r.entries, err)
+		return nil
+	}
+	r.attempts++
+	return r
+}
+
+// complete retries a batch, waiting between attempts, until it is sent,
+// spooled or dropped. It is used by Sync and Close, which wait for delivery.
+func (o *httpOutput) complete(r *retryBatch) {
+	for r != nil {
+		timer := time.NewTimer(r.delay(o.config.MaxBackoff))
+		<-timer.C
+		r = o.attempt(r)
+	}
+}
+
+// drop counts dropped entries and reports why they were dropped.
+func (o *httpOutput) drop(count int, err error) {
+	recordDropped("http", count)
+	o.config.OnError(err)
+}
+
+// spoolBatch appends a failed batch to the spool, or drops it when there is
+// no spool or the spool cannot take it.
+func (o *httpOutput) spoolBatch(batch [][]byte, cause error) {
+	if o.spool == nil {
+		o.drop(len(batch), fmt.Errorf("dropping %d entries: %w", len(batch), cause))
+		return
+	}
+	if err := o.spool.append(batch...); err != nil {
+		o.drop(len(batch), fmt.Errorf("dropping %d entries: %w", len(batch), errors.Join(cause, err)))
+	}
+}
+
+// replay sends the spooled entries in batches, once each, reporting whether
+// the spool was emptied. Batches rejected with a non-retryable status are dropped.
+func (o *httpOutput) replay() bool {
+	err := o.spool.replay(o.config.MaxBatchSize, func(batch [][]byte) error {
+		body, err := o.body(batch)
+		if err != nil {
+			o.drop(len(batch), fmt.Errorf("dropping %d spooled entries: %w", len(batch), err))
+			return nil
+		}
+		retry, err := o.post(body)
+		if err != nil && !retry {
+			o.drop(len(batch), fmt.Errorf("dropping %d spooled entries: %w", len(batch), err))
+			return nil
+		}
+		return err
+	})
+	return err == nil
+}
+
+// body encodes a batch in the configured format, compressed if configured.
+func (o *httpOutput) body(batch [][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if o.config.Gzip {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+
+	switch o.config.Format {
+	case JSONArrayBatch:
+		_, _ = w.Write([]byte("["))
+		_, _ = w.Write(bytes.Join(batch, []byte(",")))
+		_, _ = w.Write([]byte("]"))
+	default:
+		for _, encoded := range batch {
+			_, _ = w.Write(encoded)
+			_, _ = w.Write([]byte("\n"))
+		}
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// post sends a batch body once, reporting whether a failure may be retried.
+func (o *httpOutput) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, o.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for name, values := range o.config.Headers {
+		req.Header[name] = values
+	}
+	if o.config.Format == JSONArrayBatch {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	}
+	if o.config.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := o.config.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("collector responded %s", resp.Status)
+	default:
+		return false, fmt.Errorf("collector responded %s", resp.Status)
+	}
+}
+
+// jsonEntry returns the entry as a JSON object: the encoded line when it
+// already is one, or an object built from the entry otherwise.
+func jsonEntry(entry *Entry, line []byte) ([]byte, error) {
+	line = bytes.TrimSpace(line)
+	if len(line) > 0 && line[0] == '{' && json.Valid(line) {
+		return bytes.Clone(line), nil
+	}
+
+	object := make(map[string]any, len(entry.Fields)+4)
+	for _, f := range entry.Fields {
+		object[f.Key] = f.Value
+	}
+	object["timestamp"] = entry.Time.Format(time.RFC3339Nano)
+	object["severity"] = levelName(entry.Level)
+	object["message"] = entry.Message
+	if entry.Caller != nil {
+		object["caller"] = fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+	}
+	encoded, err := json.Marshal(object)
+	if err != nil {
+		// Fall back to the text of the field values that JSON cannot encode
+		for _, f := range entry.Fields {
+			object[f.Key] = fmt.Sprint(f.Value)
+		}
+		if encoded, err = json.Marshal(object); err != nil {
+			return nil, fmt.Errorf("abslog: cannot encode entry: %w", err)
+		}
+	}
+	return encoded, nil
+}
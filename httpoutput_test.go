@@ -0,0 +1,411 @@
+package abslog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// collector is a test HTTP collector recording the batches it receives.
+type collector struct {
+	*httptest.Server
+	// status returns the response status of the nth request, counting from 0
+	status func(n int) int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	batches  [][]map[string]any
+}
+
+// startCollector starts a collector responding with status, 200 if nil.
+func startCollector(t *testing.T, status func(n int) int) *collector {
+	t.Helper()
+	c := &collector{status: status}
+	c.Server = httptest.NewServer(http.HandlerFunc(c.serve))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// serve decodes and records a batch.
+func (c *collector) serve(w http.ResponseWriter, r *http.Request) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	data, _ := io.ReadAll(body)
+
+	var batch []map[string]any
+	if r.Header.Get("Content-Type") == "application/json" {
+		_ = json.Unmarshal(data, &batch)
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var entry map[string]any
+			_ = json.Unmarshal(scanner.Bytes(), &entry)
+			batch = append(batch, entry)
+		}
+	}
+
+	c.mu.Lock()
+	n := len(c.requests)
+	c.requests = append(c.requests, r)
+	c.mu.Unlock()
+
+	status := http.StatusOK
+	if c.status != nil {
+		status = c.status(n)
+	}
+	if status == http.StatusOK {
+		c.mu.Lock()
+		c.batches = append(c.batches, batch)
+		c.mu.Unlock()
+	}
+	w.WriteHeader(status)
+}
+
+// received returns the number of requests and the batches accepted.
+func (c *collector) received() (int, [][]map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.requests), append([][]map[string]any(nil), c.batches...)
+}
+
+// messages returns the messages of the accepted entries, in order.
+func (c *collector) messages() []string {
+	_, batches := c.received()
+	var messages []string
+	for _, batch := range batches {
+		for _, entry := range batch {
+			messages = append(messages, entry["message"].(string))
+		}
+	}
+	return messages
+}
+
+// droppedCount returns the number of entries dropped by the given kind of output.
+func droppedCount(output string) int64 {
+	return counter(metrics.dropped, output)
+}
+
+func TestHTTPOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      HTTPBatchFormat
+		gzip        bool
+		contentType string
+	}{
+		{"ndjson", NDJSONBatch, false, "application/x-ndjson"},
+		{"json array", JSONArrayBatch, false, "application/json"},
+		{"gzip ndjson", NDJSONBatch, true, "application/x-ndjson"},
+		{"gzip json array", JSONArrayBatch, true, "application/json"},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				c := startCollector(t, nil)
+				out, err := NewHTTPOutput(HTTPOutputConfig{
+					URL:     c.URL,
+					Format:  tt.format,
+					Gzip:    tt.gzip,
+					Headers: http.Header{"Authorization": {"Bearer t"}},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer out.Close()
+
+				builder, _ := newCaptureBuilder(backend.typ, JSONEncoder)
+				logger := builder.Output(out).Build()
+				logger.Info("first")
+				logger.With("user", "ann").Warn("second")
+				if err := out.Sync(); err != nil {
+					t.Fatal(err)
+				}
+
+				requests, batches := c.received()
+				if requests != 1 || len(batches[0]) != 2 {
+					t.Fatalf("got %d requests, want one batch of 2 entries", requests)
+				}
+				req := c.requests[0]
+				if req.Header.Get("Content-Type") != tt.contentType || req.Header.Get("Authorization") != "Bearer t" {
+					t.Errorf("headers = %v", req.Header)
+				}
+				if gzipped := req.Header.Get("Content-Encoding") == "gzip"; gzipped != tt.gzip {
+					t.Errorf("gzip = %v, want %v", gzipped, tt.gzip)
+				}
+				// JSON lines are sent as encoded
+				second := batches[0][1]
+				if severity, _ := second["severity"].(string); second["message"] != "second" || !strings.HasPrefix(severity, "WARN") {
+					t.Errorf("second entry = %v", second)
+				}
+			})
+		}
+	}
+}
+
+func TestHTTPOutputNonJSONEncoder(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			c := startCollector(t, nil)
+			out, err := NewHTTPOutput(HTTPOutputConfig{URL: c.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+
+			builder, _ := newCaptureBuilder(backend.typ, ConsoleEncoder)
+			builder.Output(out).Build().With("count", 3).Error("failed")
+			_ = out.Sync()
+
+			_, batches := c.received()
+			entry := batches[0][0]
+			if entry["message"] != "failed" || entry["severity"] != "error" || entry["count"] != float64(3) {
+				t.Errorf("entry = %v", entry)
+			}
+			if _, err := time.Parse(time.RFC3339Nano, entry["timestamp"].(string)); err != nil {
+				t.Errorf("timestamp: %v", err)
+			}
+			if caller, _ := entry["caller"].(string); !strings.Contains(caller, "httpoutput_test.go:") {
+				t.Errorf("caller = %q, want this test", caller)
+			}
+		})
+	}
+}
+
+func TestHTTPOutputBatching(t *testing.T) {
+	c := startCollector(t, nil)
+	out, err := NewHTTPOutput(HTTPOutputConfig{URL: c.URL, MaxBatchSize: 2, MaxBatchAge: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	logger, _ := newCaptureBuilder(ZapLogger, JSONEncoder)
+	l := logger.Output(out).Build()
+
+	// Full batches are sent right away, the last one once it is old enough
+	for _, msg := range []string{"a", "b", "c", "d", "e"} {
+		l.Info(msg)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		requests, batches := c.received()
+		if requests == 3 {
+			if len(batches[0]) != 2 || len(batches[1]) != 2 || len(batches[2]) != 1 {
+				t.Errorf("batch sizes = %d, %d, %d, want 2, 2, 1", len(batches[0]), len(batches[1]), len(batches[2]))
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d requests, want 3", requests)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := strings.Join(c.messages(), ""); got != "abcde" {
+		t.Errorf("messages = %q, want abcde", got)
+	}
+}
+
+func TestHTTPOutputRetries(t *testing.T) {
+	tests := []struct {
+		name   string
+		status func(n int) int
+		// requests is the number of requests made for the batch
+		requests  int
+		delivered bool
+	}{
+		{"retried until accepted", func(n int) int { return []int{503, 429, 200}[min(n, 2)] }, 3, true},
+		{"out of retries", func(int) int { return http.StatusBadGateway }, 3, false},
+		{"not retryable", func(int) int { return http.StatusBadRequest }, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := startCollector(t, tt.status)
+			var reported []error
+			out, err := NewHTTPOutput(HTTPOutputConfig{
+				URL:        c.URL,
+				MaxRetries: 2,
+				MinBackoff: time.Millisecond,
+				MaxBackoff: 2 * time.Millisecond,
+				OnError:    func(err error) { reported = append(reported, err) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+
+			dropped := droppedCount("http")
+			if err := out.WriteEntry(&Entry{Level: InfoLevel, Message: "msg"}, []byte(`{"message":"msg"}`)); err != nil {
+				t.Fatal(err)
+			}
+			_ = out.Sync()
+
+			requests, batches := c.received()
+			if requests != tt.requests || (len(batches) == 1) != tt.delivered {
+				t.Errorf("got %d requests and %d accepted batches, want %d requests, delivered %v", requests, len(batches), tt.requests, tt.delivered)
+			}
+			wantDropped := 0
+			if !tt.delivered {
+				wantDropped = 1
+			}
+			if got := droppedCount("http") - dropped; got != int64(wantDropped) || len(reported) != wantDropped {
+				t.Errorf("dropped %d entries with errors %v, want %d", got, reported, wantDropped)
+			}
+		})
+	}
+}
+
+func TestHTTPOutputIntakeDuringBackoff(t *testing.T) {
+	// The collector fails until accepting is set
+	var accepting atomic.Bool
+	c := startCollector(t, func(int) int {
+		if accepting.Load() {
+			return http.StatusOK
+		}
+		return http.StatusServiceUnavailable
+	})
+	out, err := NewHTTPOutput(HTTPOutputConfig{
+		URL:          c.URL,
+		MaxBatchSize: 10,
+		MaxBatchAge:  time.Millisecond,
+		QueueSize:    5,
+		MaxRetries:   1000,
+		MinBackoff:   20 * time.Millisecond,
+		MaxBackoff:   20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := out.(*httpOutput)
+	recorder := &droppingRecorder{}
+	SetMetricsRecorder(recorder)
+	t.Cleanup(func() { SetMetricsRecorder(nil) })
+
+	write := func(count int) {
+		t.Helper()
+		for i := 0; i < count; i++ {
+			start := time.Now()
+			if err := out.WriteEntry(&Entry{Level: InfoLevel}, []byte(`{"message":"m"}`)); err != nil {
+				t.Fatalf("WriteEntry() = %v, want entries dropped without error", err)
+			}
+			if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+				t.Fatalf("WriteEntry() blocked for %v", elapsed)
+			}
+		}
+	}
+	waitQueue := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for len(o.queue) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("queue holds %d entries, want %d", len(o.queue), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The first batch fails and waits for its retry
+	write(1)
+	for requests, _ := c.received(); requests == 0; requests, _ = c.received() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Entries keep filling the next batch, then the queue, then are dropped
+	dropped := droppedCount("http")
+	write(5)
+	waitQueue(0)
+	write(5)
+	waitQueue(0)
+	write(5)
+	waitQueue(5)
+	write(3)
+	if got := droppedCount("http") - dropped; got != 3 {
+		t.Errorf("dropped %d entries, want 3", got)
+	}
+	if recorder.count() != 3 {
+		t.Errorf("DropRecorder received %d dropped entries, want 3", recorder.count())
+	}
+
+	accepting.Store(true)
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(c.messages()); got != 16 {
+		t.Errorf("delivered %d entries, want 16", got)
+	}
+}
+
+// droppingRecorder is a MetricsRecorder and DropRecorder counting dropped entries.
+type droppingRecorder struct {
+	dropped atomic.Int64
+}
+
+func (r *droppingRecorder) RecordEntry(LogLevel, string) {}
+
+func (r *droppingRecorder) RecordDropped(output string, count int) {
+	if output == "http" {
+		r.dropped.Add(int64(count))
+	}
+}
+
+func (r *droppingRecorder) count() int64 {
+	return r.dropped.Load()
+}
+
+func TestHTTPOutputClose(t *testing.T) {
+	c := startCollector(t, nil)
+	out, err := NewHTTPOutput(HTTPOutputConfig{URL: c.URL, MaxBatchAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = out.WriteEntry(&Entry{Level: InfoLevel}, []byte(`{"message":"pending"}`))
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.messages(); len(got) != 1 || got[0] != "pending" {
+		t.Errorf("delivered %q, want the pending entry sent on Close", got)
+	}
+	if err := out.WriteEntry(&Entry{Level: InfoLevel}, []byte(`{}`)); !errors.Is(err, errOutputClosed) {
+		t.Errorf("WriteEntry() after Close = %v, want errOutputClosed", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if err := out.Sync(); err != nil {
+		t.Errorf("Sync() after Close = %v", err)
+	}
+}
+
+func TestHTTPOutputConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config HTTPOutputConfig
+		err    string
+	}{
+		{"missing URL", HTTPOutputConfig{}, "URL is required"},
+		{"invalid format", HTTPOutputConfig{URL: "http://localhost", Format: JSONArrayBatch + 1}, "invalid HTTP batch format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPOutput(tt.config); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("NewHTTPOutput() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
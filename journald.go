@@ -0,0 +1,192 @@
+package abslog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// defaultJournaldSocket is the journald native protocol socket.
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+// journaldMaxFieldName is the maximum length of a journal field name.
+const journaldMaxFieldName = 64
+
+// journaldReservedFields are the field names written by the journald output.
+// Entry fields taking them get a FIELD_ prefix, so that entries have a single
+// MESSAGE, PRIORITY, etc.
+var journaldReservedFields = map[string]bool{
+	"MESSAGE": true, "PRIORITY": true, "SYSLOG_IDENTIFIER": true,
+	"CODE_FILE": true, "CODE_LINE": true, "CODE_FUNC": true,
+}
+
+// JournaldConfig configures a journald output.
+type JournaldConfig struct {
+	// SocketPath is the journald socket. If empty, /run/systemd/journal/socket is used.
+	SocketPath string
+	// Identifier is written as SYSLOG_IDENTIFIER.
+	// If empty, the name of the executable is used.
+	Identifier string
+}
+
+// journaldOutput writes entries to journald with the native protocol.
+type journaldOutput struct {
+	conn       *net.UnixConn
+	addr       *net.UnixAddr
+	identifier string
+}
+
+// NewJournaldOutput creates an output sending entries to journald with the
+// native protocol, so that they are stored with structured fields: MESSAGE,
+// PRIORITY, SYSLOG_IDENTIFIER, CODE_FILE, CODE_LINE, CODE_FUNC and the entry
+// fields and context values with uppercase names, prefixed with FIELD_ when
+// they take one of the names above. Field values that are not
+// strings are JSON encoded. Entries too large for a datagram are passed
+// through a temporary file, as journald expects.
+// It fails if the journald socket does not exist.
+func NewJournaldOutput(config JournaldConfig) (Output, error) {
+	if config.SocketPath == "" {
+		config.SocketPath = defaultJournaldSocket
+	}
+	if config.Identifier == "" {
+		config.Identifier = filepath.Base(os.Args[0])
+	}
+	if _, err := os.Stat(config.SocketPath); err != nil {
+		return nil, fmt.Errorf("abslog: journald socket not available: %w", err)
+	}
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("abslog: cannot open journald connection: %w", err)
+	}
+	return &journaldOutput{
+		conn:       conn,
+		addr:       &net.UnixAddr{Name: config.SocketPath, Net: "unixgram"},
+		identifier: config.Identifier,
+	}, nil
+}
+
+// ctxAsFields reports that context values must be sent as journal fields.
+func (o *journaldOutput) ctxAsFields() bool {
+	return true
+}
+
+// WriteEntry sends the entry to journald. The encoded line is not used.
+func (o *journaldOutput) WriteEntry(entry *Entry, _ []byte) error {
+	payload := o.payload(entry)
+
+	_, _, err := o.conn.WriteMsgUnix(payload, nil, o.addr)
+	if err == nil {
+		return nil
+	}
+	if !isMessageTooLong(err) {
+		return err
+	}
+	return sendJournaldFile(o.conn, o.addr, payload)
+}
+
+// payload encodes the entry in the journald native protocol.
+func (o *journaldOutput) payload(entry *Entry) []byte {
+	var buf bytes.Buffer
+	writeJournaldField(&buf, "MESSAGE", entry.Message)
+	writeJournaldField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(entry.Level)))
+	writeJournaldField(&buf, "SYSLOG_IDENTIFIER", o.identifier)
+	if entry.Caller != nil {
+		writeJournaldField(&buf, "CODE_FILE", entry.Caller.File)
+		writeJournaldField(&buf, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		writeJournaldField(&buf, "CODE_FUNC", entry.Caller.Function)
+	}
+	for _, f := range entry.Fields {
+		name := journaldFieldName(f.Key)
+		if name == "" {
+			continue
+		}
+		writeJournaldField(&buf, name, journaldFieldValue(f.Value))
+	}
+	return buf.Bytes()
+}
+
+// writeJournaldField writes a field in the native protocol: "NAME=value\n",
+// or the name, the little-endian 64-bit length and the value for values
+// containing newlines.
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	_ = binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journaldFieldName converts a field key to a valid journal field name:
+// uppercase letters, digits and underscores, not starting with an underscore
+// or a digit, at most 64 characters, and prefixed with FIELD_ when taking a
+// name written by the output. It returns "" when nothing is left.
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	// Names starting with an underscore are reserved for trusted fields
+	name = strings.TrimLeft(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "F_" + name
+	}
+	if len(name) > journaldMaxFieldName {
+		name = name[:journaldMaxFieldName]
+	}
+	if journaldReservedFields[name] {
+		name = "FIELD_" + name
+	}
+	return name
+}
+
+// journaldFieldValue returns strings as is and JSON encodes other values.
+func journaldFieldValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if b, err := json.Marshal(value); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// isMessageTooLong reports whether err means that the datagram is too large.
+func isMessageTooLong(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// Sync does nothing, since entries are not buffered.
+func (o *journaldOutput) Sync() error {
+	return nil
+}
+
+// Close closes the connection to journald.
+func (o *journaldOutput) Close() error {
+	return o.conn.Close()
+}
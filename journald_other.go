@@ -0,0 +1,13 @@
+//go:build !unix
+
+package abslog
+
+import (
+	"errors"
+	"net"
+)
+
+// sendJournaldFile fails, since descriptors cannot be passed on this platform.
+func sendJournaldFile(_ *net.UnixConn, _ *net.UnixAddr, _ []byte) error {
+	return errors.New("abslog: journald entry too large")
+}
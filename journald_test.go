@@ -0,0 +1,218 @@
+//go:build unix
+
+package abslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// journaldServer is a local journald socket returning the payloads it receives.
+type journaldServer struct {
+	path string
+	conn *net.UnixConn
+}
+
+// startJournaldServer listens on a journald socket in a temporary directory.
+func startJournaldServer(t *testing.T) *journaldServer {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &journaldServer{path: path, conn: conn}
+}
+
+// receive returns the fields of the next payload, read from the passed
+// descriptor when the payload did not fit in a datagram.
+func (s *journaldServer) receive(t *testing.T) map[string]string {
+	t.Helper()
+	buf := make([]byte, 1<<20)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_ = s.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, oobn, _, _, err := s.conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := buf[:n]
+
+	if oobn > 0 {
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			t.Fatal(err)
+		}
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := os.NewFile(uintptr(fds[0]), "payload")
+		defer f.Close()
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if payload, err = io.ReadAll(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return parseJournaldPayload(t, payload)
+}
+
+// parseJournaldPayload decodes a payload of the journald native protocol.
+func parseJournaldPayload(t *testing.T, payload []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(payload) > 0 {
+		eol := bytes.IndexByte(payload, '\n')
+		if eol < 0 {
+			t.Fatalf("unterminated field %q", payload)
+		}
+		if eq := bytes.IndexByte(payload[:eol], '='); eq >= 0 {
+			fields[string(payload[:eq])] = string(payload[eq+1 : eol])
+			payload = payload[eol+1:]
+			continue
+		}
+		name := string(payload[:eol])
+		payload = payload[eol+1:]
+		if len(payload) < 8 {
+			t.Fatalf("field %s has no length", name)
+		}
+		size := binary.LittleEndian.Uint64(payload)
+		payload = payload[8:]
+		if uint64(len(payload)) < size+1 || payload[size] != '\n' {
+			t.Fatalf("field %s of %d bytes is truncated", name, size)
+		}
+		fields[name] = string(payload[:size])
+		payload = payload[size+1:]
+	}
+	return fields
+}
+
+func TestJournaldOutput(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			server := startJournaldServer(t)
+			out, err := NewJournaldOutput(JournaldConfig{SocketPath: server.path, Identifier: "myapp"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+
+			// Console loggers write context values in the message, except for journald
+			builder, _ := newCaptureBuilder(backend.typ, ConsoleEncoder)
+			logger := builder.Output(out).Build()
+			ctx := WithValues(context.Background(), "request_id", "r1")
+			logger.Named("api").With("user", "ann", "count", 3, "multi", "a\nb", "_trusted", "x", "1st", "y",
+				"message", "user message", "Priority", 7, "code_file", "f.go").
+				WarnCtx(ctx, "warned")
+
+			fields := server.receive(t)
+			want := map[string]string{
+				"MESSAGE":           "warned",
+				"PRIORITY":          "4",
+				"SYSLOG_IDENTIFIER": "myapp",
+				"USER":              "ann",
+				"COUNT":             "3",
+				"MULTI":             "a\nb",
+				"REQUEST_ID":        "r1",
+				"TRUSTED":           "x",
+				"F_1ST":             "y",
+				"FIELD_MESSAGE":     "user message",
+				"FIELD_PRIORITY":    "7",
+				"FIELD_CODE_FILE":   "f.go",
+			}
+			for name, value := range want {
+				if fields[name] != value {
+					t.Errorf("%s = %q, want %q", name, fields[name], value)
+				}
+			}
+			if filepath.Base(fields["CODE_FILE"]) != "journald_test.go" || fields["CODE_LINE"] == "" ||
+				!strings.HasSuffix(fields["CODE_FUNC"], "TestJournaldOutput.func1") {
+				t.Errorf("code location = %s:%s %s, want this test", fields["CODE_FILE"], fields["CODE_LINE"], fields["CODE_FUNC"])
+			}
+		})
+	}
+}
+
+func TestJournaldLargeEntry(t *testing.T) {
+	server := startJournaldServer(t)
+	out, err := NewJournaldOutput(JournaldConfig{SocketPath: server.path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	// Larger than the maximum datagram size
+	message := strings.Repeat("x", 4<<20)
+	if err := out.WriteEntry(&Entry{Level: ErrorLevel, Message: message}, nil); err != nil {
+		t.Fatal(err)
+	}
+	fields := server.receive(t)
+	if fields["MESSAGE"] != message || fields["PRIORITY"] != "3" {
+		t.Errorf("got a %d bytes message with priority %s, want %d bytes and priority 3",
+			len(fields["MESSAGE"]), fields["PRIORITY"], len(message))
+	}
+	if fields["SYSLOG_IDENTIFIER"] != filepath.Base(os.Args[0]) {
+		t.Errorf("SYSLOG_IDENTIFIER = %q, want the executable name", fields["SYSLOG_IDENTIFIER"])
+	}
+}
+
+func TestJournaldMissingSocket(t *testing.T) {
+	_, err := NewJournaldOutput(JournaldConfig{SocketPath: filepath.Join(t.TempDir(), "missing.sock")})
+	if err == nil || !strings.Contains(err.Error(), "journald socket not available") {
+		t.Errorf("NewJournaldOutput() error = %v, want the socket to be reported missing", err)
+	}
+}
+
+func TestJournaldFieldName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"user", "USER"},
+		{"http.status-code", "HTTP_STATUS_CODE"},
+		{"__cursor", "CURSOR"},
+		{"2fa", "F_2FA"},
+		{"syslog_identifier", "FIELD_SYSLOG_IDENTIFIER"},
+		{"code.line", "FIELD_CODE_LINE"},
+		{"___", ""},
+		{"日本", ""},
+		{strings.Repeat("a", 70), strings.Repeat("A", 64)},
+	}
+	for _, tt := range tests {
+		if got := journaldFieldName(tt.key); got != tt.want {
+			t.Errorf("journaldFieldName(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestJournaldFieldValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{"text", "text"},
+		{errors.New("boom"), "boom"},
+		{time.Second, "1s"},
+		{42, "42"},
+		{map[string]int{"a": 1}, `{"a":1}`},
+		{[]string{"x", "y"}, `["x","y"]`},
+		{func() {}, "0x"},
+	}
+	for _, tt := range tests {
+		if got := journaldFieldValue(tt.value); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("journaldFieldValue(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package abslog
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendJournaldFile sends a payload too large for a datagram by writing it to
+// an unlinked temporary file and passing its descriptor to journald.
+func sendJournaldFile(conn *net.UnixConn, addr *net.UnixAddr, payload []byte) error {
+	dir := "/dev/shm"
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "abslog-journal-")
+	if err != nil {
+		return fmt.Errorf("abslog: cannot create journald payload file: %w", err)
+	}
+	defer f.Close()
+	_ = os.Remove(f.Name())
+
+	if _, err := f.Write(payload); err != nil {
+		return fmt.Errorf("abslog: cannot write journald payload file: %w", err)
+	}
+	_, _, err = conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), addr)
+	return err
+}
@@ -0,0 +1,128 @@
+package abslog
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// builtinLevels lists the built-in levels from the least to the most severe.
+var builtinLevels = []LogLevel{TraceLevel, DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel}
+
+// builtinLevelNames holds the names of the built-in levels.
+var builtinLevelNames = map[LogLevel]string{
+	TraceLevel: "trace",
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+	PanicLevel: "panic",
+	FatalLevel: "fatal",
+}
+
+// legacyLevels are the built-in levels in the order of their legacy values,
+// from DebugLevel = 1 to FatalLevel = 6.
+var legacyLevels = []LogLevel{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, PanicLevel, FatalLevel}
+
+// customLevels holds the levels registered with RegisterLevel.
+var customLevels = struct {
+	sync.RWMutex
+	names map[LogLevel]string
+}{names: make(map[LogLevel]string)}
+
+// RegisterLevel registers a custom level with the given name.
+// The level value is its severity: it must lie strictly between TraceLevel and
+// PanicLevel and must not be a built-in level, e.g. 35 for a "notice" level
+// between InfoLevel and WarnLevel. Names are compared case-insensitively and
+// must be unique.
+//
+// Custom levels are enabled, routed and written by the backends like the
+// closest built-in level below them, and appear with their own name in output.
+func RegisterLevel(level LogLevel, name string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return fmt.Errorf("abslog: custom level %d has an empty name", level)
+	}
+	if level <= TraceLevel || level >= PanicLevel {
+		return fmt.Errorf("abslog: custom level %q must be between %d and %d, got %d", name, TraceLevel, PanicLevel, level)
+	}
+	if builtin, ok := builtinLevelNames[level]; ok {
+		return fmt.Errorf("abslog: level %d is the built-in level %q", level, builtin)
+	}
+	for _, builtin := range builtinLevelNames {
+		if builtin == name {
+			return fmt.Errorf("abslog: level name %q is used by a built-in level", name)
+		}
+	}
+
+	customLevels.Lock()
+	defer customLevels.Unlock()
+
+	if existing, ok := customLevels.names[level]; ok {
+		return fmt.Errorf("abslog: level %d is already registered as %q", level, existing)
+	}
+	for l, n := range customLevels.names {
+		if n == name {
+			return fmt.Errorf("abslog: level name %q is already registered for level %d", name, l)
+		}
+	}
+	customLevels.names[level] = name
+	return nil
+}
+
+// normalizeLevel returns the built-in level of a legacy level value (see
+// LogLevel) and any other level unchanged.
+func normalizeLevel(level LogLevel) LogLevel {
+	if level >= 1 && int(level) <= len(legacyLevels) {
+		return legacyLevels[level-1]
+	}
+	return level
+}
+
+// minimumLevel returns the minimum level of a logger configured with level:
+// built-in and registered levels are kept, and any other value falls back to
+// defaultLogLevel on both backends.
+func minimumLevel(level LogLevel) LogLevel {
+	level = normalizeLevel(level)
+	if isBuiltinLevel(level) {
+		return level
+	}
+	customLevels.RLock()
+	defer customLevels.RUnlock()
+	if _, ok := customLevels.names[level]; ok {
+		return level
+	}
+	return defaultLogLevel
+}
+
+// levelName returns the name of a built-in or custom level,
+// or "level(N)" for unknown levels.
+func levelName(level LogLevel) string {
+	level = normalizeLevel(level)
+	if name, ok := builtinLevelNames[level]; ok {
+		return name
+	}
+	customLevels.RLock()
+	defer customLevels.RUnlock()
+	if name, ok := customLevels.names[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", level)
+}
+
+// isBuiltinLevel reports whether level is one of the built-in levels.
+func isBuiltinLevel(level LogLevel) bool {
+	_, ok := builtinLevelNames[level]
+	return ok
+}
+
+// baseLevel returns the closest built-in level at or below level,
+// or TraceLevel for levels below every built-in level.
+func baseLevel(level LogLevel) LogLevel {
+	for i := len(builtinLevels) - 1; i >= 0; i-- {
+		if builtinLevels[i] <= level {
+			return builtinLevels[i]
+		}
+	}
+	return TraceLevel
+}
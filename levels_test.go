@@ -0,0 +1,202 @@
+package abslog
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Custom levels registered by registerTestLevels
+const (
+	testNoticeLevel LogLevel = 35
+	testAuditLevel  LogLevel = 55
+)
+
+// registerTestLevels registers the custom levels used by tests, once per test binary.
+var registerTestLevels = sync.OnceFunc(func() {
+	if err := RegisterLevel(testNoticeLevel, "notice"); err != nil {
+		panic(err)
+	}
+	if err := RegisterLevel(testAuditLevel, "Audit"); err != nil {
+		panic(err)
+	}
+})
+
+func TestRegisterLevel(t *testing.T) {
+	registerTestLevels()
+
+	tests := []struct {
+		name  string
+		level LogLevel
+		label string
+		err   string
+	}{
+		{"empty name", 36, " ", "empty name"},
+		{"at trace", TraceLevel, "lowest", "must be between"},
+		{"below trace", 5, "legacy", "must be between"},
+		{"at panic", PanicLevel, "highest", "must be between"},
+		{"built-in value", InfoLevel, "information", "built-in level"},
+		{"built-in name", 36, "WARN", "used by a built-in level"},
+		{"registered value", testNoticeLevel, "other", "already registered"},
+		{"registered name", 36, "NOTICE", "already registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterLevel(tt.level, tt.label)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("RegisterLevel(%d, %q) = %v, want an error containing %q", tt.level, tt.label, err, tt.err)
+			}
+		})
+	}
+
+	if got := testAuditLevel.String(); got != "audit" {
+		t.Errorf("custom level name = %q, want the lowercased name", got)
+	}
+	if got := LogLevel(42).String(); got != "level(42)" {
+		t.Errorf("unknown level name = %q, want level(42)", got)
+	}
+}
+
+func TestBaseLevel(t *testing.T) {
+	tests := []struct {
+		level LogLevel
+		want  LogLevel
+	}{
+		{TraceLevel, TraceLevel},
+		{testNoticeLevel, InfoLevel},
+		{testAuditLevel, ErrorLevel},
+		{FatalLevel, FatalLevel},
+		{-5, TraceLevel},
+	}
+	for _, tt := range tests {
+		if got := baseLevel(tt.level); got != tt.want {
+			t.Errorf("baseLevel(%d) = %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestLegacyLevels(t *testing.T) {
+	tests := []struct {
+		legacy LogLevel
+		want   LogLevel
+	}{
+		{1, DebugLevel},
+		{2, InfoLevel},
+		{3, WarnLevel},
+		{4, ErrorLevel},
+		{5, PanicLevel},
+		{6, FatalLevel},
+	}
+	for _, tt := range tests {
+		if got := normalizeLevel(tt.legacy); got != tt.want {
+			t.Errorf("normalizeLevel(%d) = %v, want %v", tt.legacy, got, tt.want)
+		}
+		if got, want := tt.legacy.String(), tt.want.String(); got != want {
+			t.Errorf("LogLevel(%d).String() = %q, want %q", tt.legacy, got, want)
+		}
+		if getZapLevel(tt.legacy) != getZapLevel(tt.want) || getLogrusLevel(tt.legacy) != getLogrusLevel(tt.want) {
+			t.Errorf("legacy level %d does not map to the backend levels of %v", tt.legacy, tt.want)
+		}
+	}
+	if got := normalizeLevel(TraceLevel); got != TraceLevel {
+		t.Errorf("normalizeLevel(TraceLevel) = %v", got)
+	}
+
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			builder, out := newCaptureBuilder(backend.typ, JSONEncoder)
+			// Legacy warn level
+			logger := builder.LogLevel(3).Build()
+			logger.Info("filtered")
+			logger.Log(2, "filtered")
+			logger.Log(4, "legacy error")
+			logger.Warn("warn")
+
+			if out.count() != 2 {
+				t.Fatalf("got %d entries, want 2", out.count())
+			}
+			if entry := out.entries[0]; entry.Level != ErrorLevel || entry.Message != "legacy error" {
+				t.Errorf("got %v %q, want error \"legacy error\"", entry.Level, entry.Message)
+			}
+		})
+	}
+}
+
+func TestInvalidLoggerLevel(t *testing.T) {
+	for _, backend := range testBackends {
+		for _, level := range []LogLevel{0, 8, 45, -10, 100} {
+			t.Run(backend.name+"/"+level.String(), func(t *testing.T) {
+				builder, out := newCaptureBuilder(backend.typ, JSONEncoder)
+				logger := builder.LogLevel(level).Build()
+				logger.Trace("filtered")
+				logger.Debug("filtered")
+				logger.Info("info")
+				logger.Warn("warn")
+
+				if out.count() != 2 {
+					t.Fatalf("got %d entries, want the info and warn entries", out.count())
+				}
+				if entry := out.entries[0]; entry.Level != InfoLevel {
+					t.Errorf("first entry level = %v, want info", entry.Level)
+				}
+			})
+		}
+	}
+}
+
+func TestLevelsLogged(t *testing.T) {
+	registerTestLevels()
+
+	tests := []struct {
+		level LogLevel
+		// console is written in the console line, compared case-insensitively
+		console string
+		// severity is the severity written by the JSON encoder
+		severity string
+		// enabledAt is a logger level below which the entry is dropped
+		enabledAt LogLevel
+	}{
+		{TraceLevel, "trace", "DEBUG", TraceLevel},
+		{DebugLevel, "debug", "DEBUG", DebugLevel},
+		{testNoticeLevel, "notice", "NOTICE", InfoLevel},
+		{testAuditLevel, "audit", "AUDIT", ErrorLevel},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.level.String(), func(t *testing.T) {
+				console, out := newCaptureLogger(backend.typ, ConsoleEncoder)
+				console.Log(tt.level, "msg")
+				entry, line := out.last(t)
+				if entry.Level != tt.level {
+					t.Errorf("level = %v, want %v", entry.Level, tt.level)
+				}
+				assertContains(t, strings.ToLower(line), tt.console)
+
+				structured, out := newCaptureLogger(backend.typ, JSONEncoder)
+				structured.Log(tt.level, "msg")
+				_, line = out.last(t)
+				var decoded struct {
+					Severity string `json:"severity"`
+				}
+				if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+					t.Fatal(err)
+				}
+				if decoded.Severity != tt.severity {
+					t.Errorf("severity = %q, want %q", decoded.Severity, tt.severity)
+				}
+
+				// The entry is enabled like its base level
+				builder, out := newCaptureBuilder(backend.typ, JSONEncoder)
+				logger := builder.LogLevel(tt.enabledAt).Build()
+				logger.Log(tt.level, "enabled")
+				above, _ := newCaptureBuilder(backend.typ, JSONEncoder)
+				above.LogLevel(tt.enabledAt+10).Output(out).Build().Log(tt.level, "disabled")
+				if out.count() != 1 {
+					t.Errorf("got %d entries, want only the entry logged at level %v", out.count(), tt.enabledAt)
+				}
+			})
+		}
+	}
+}
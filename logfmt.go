@@ -0,0 +1,127 @@
+package abslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+)
+
+// encodeLogfmt writes an entry as a logfmt line:
+// ts=... level=... caller=... msg="..." key=value ... stacktrace="...".
+func encodeLogfmt(buf *buffer.Buffer, entry *Entry, stack string) {
+	buf.AppendString("ts=")
+	buf.AppendString(formatEntryTime(entry.Time))
+	buf.AppendString(" level=")
+	appendLogfmtString(buf, levelName(entry.Level))
+	if entry.Caller != nil {
+		buf.AppendString(" caller=")
+		appendLogfmtString(buf, shortCaller(entry.Caller))
+	}
+	buf.AppendString(" msg=")
+	appendLogfmtString(buf, entry.Message)
+	for _, f := range entry.Fields {
+		buf.AppendByte(' ')
+		buf.AppendString(logfmtKey(f.Key))
+		buf.AppendByte('=')
+		appendLogfmtValue(buf, f.Value)
+	}
+	if stack != "" {
+		buf.AppendString(" " + stackKey + "=")
+		appendLogfmtString(buf, stack)
+	}
+	buf.AppendByte('\n')
+}
+
+// logfmtKey returns key with the characters that cannot appear in a logfmt
+// key (spaces, '=', '"' and control characters) replaced by underscores.
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// appendLogfmtValue writes a value: strings, errors and Stringers as text,
+// numbers and booleans as is, times in RFC 3339 and other values as JSON.
+func appendLogfmtValue(buf *buffer.Buffer, value any) {
+	switch v := value.(type) {
+	case nil:
+		buf.AppendString("null")
+	case string:
+		appendLogfmtString(buf, v)
+	case []byte:
+		appendLogfmtString(buf, string(v))
+	case error:
+		appendLogfmtString(buf, v.Error())
+	case time.Time:
+		buf.AppendString(v.Format(time.RFC3339Nano))
+	case fmt.Stringer:
+		appendLogfmtString(buf, v.String())
+	case bool:
+		buf.AppendBool(v)
+	case int:
+		buf.AppendInt(int64(v))
+	case int8:
+		buf.AppendInt(int64(v))
+	case int16:
+		buf.AppendInt(int64(v))
+	case int32:
+		buf.AppendInt(int64(v))
+	case int64:
+		buf.AppendInt(v)
+	case uint:
+		buf.AppendUint(uint64(v))
+	case uint8:
+		buf.AppendUint(uint64(v))
+	case uint16:
+		buf.AppendUint(uint64(v))
+	case uint32:
+		buf.AppendUint(uint64(v))
+	case uint64:
+		buf.AppendUint(v)
+	case float32:
+		buf.AppendFloat(float64(v), 32)
+	case float64:
+		buf.AppendFloat(v, 64)
+	default:
+		if b, err := json.Marshal(v); err == nil {
+			appendLogfmtString(buf, string(b))
+			return
+		}
+		appendLogfmtString(buf, fmt.Sprint(v))
+	}
+}
+
+// appendLogfmtString writes s, quoted and escaped when it is empty or holds
+// spaces, '=', '"', '\\' or non-printable characters.
+func appendLogfmtString(buf *buffer.Buffer, s string) {
+	if !logfmtNeedsQuote(s) {
+		buf.AppendString(s)
+		return
+	}
+	buf.AppendString(strconv.Quote(s))
+}
+
+// logfmtNeedsQuote reports whether s must be quoted in a logfmt value.
+func logfmtNeedsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package abslog
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/buffer"
+)
+
+func TestLogfmtEncoder(t *testing.T) {
+	ctx := WithValues(context.Background(), "request_id", "r1")
+
+	tests := []struct {
+		name string
+		log  func(logger AbsLog)
+		// want matches the line without its timestamp and caller
+		want string
+	}{
+		{"message", func(l AbsLog) { l.Info("hello") }, `^level=info msg=hello$`},
+		{"quoted message", func(l AbsLog) { l.Warn("hello world") }, `^level=warn msg="hello world"$`},
+		{"fields", func(l AbsLog) { l.With("user", "ann", "n", 3, "ok", true).Debug("m") }, `^level=debug msg=m n=3 ok=true user=ann$`},
+		{"context values", func(l AbsLog) { l.InfoCtx(ctx, "m") }, `^level=info msg=m request_id=r1$`},
+		{"escaped key and value", func(l AbsLog) { l.With("a b", "x=y").Info("m") }, `^level=info msg=m a_b="x=y"$`},
+		{"error with stack", func(l AbsLog) { l.ErrorErr(errors.New("boom"), "failed") },
+			`^level=error msg=failed error=boom error_type=\*errors.errorString stacktrace=".*TestLogfmtEncoder.*"$`},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, LogfmtEncoder)
+				tt.log(logger)
+				_, line := out.last(t)
+
+				if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+					t.Errorf("line %q is not a single line", line)
+				}
+				ts, rest, _ := strings.Cut(strings.TrimSuffix(line, "\n"), " ")
+				if _, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(ts, "ts=")); err != nil {
+					t.Errorf("line %q does not start with a timestamp: %v", line, err)
+				}
+				rest = regexp.MustCompile(` caller=\S+`).ReplaceAllString(rest, "")
+				if !regexp.MustCompile(tt.want).MatchString(rest) {
+					t.Errorf("line %q, want a match of %s", rest, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestLogfmtCaller(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, LogfmtEncoder)
+			logger.Info("m")
+			_, line := out.last(t)
+			if !regexp.MustCompile(` caller=\S*/logfmt_test.go:\d+ `).MatchString(line) {
+				t.Errorf("line %q, want the caller of the logging call", line)
+			}
+		})
+	}
+}
+
+func TestLogfmtValue(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{nil, "null"},
+		{"plain", "plain"},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{`a"b`, `"a\"b"`},
+		{`a\b`, `"a\\b"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{[]byte("bytes"), "bytes"},
+		{errors.New("an error"), `"an error"`},
+		{time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), "2024-05-01T12:00:00Z"},
+		{time.Second, "1s"},
+		{true, "true"},
+		{-7, "-7"},
+		{uint8(7), "7"},
+		{1.5, "1.5"},
+		{map[string]int{"a": 1}, `"{\"a\":1}"`},
+		{make(chan int), ""},
+	}
+	for _, tt := range tests {
+		buf := buffer.NewPool().Get()
+		appendLogfmtValue(buf, tt.value)
+		got := buf.String()
+		if tt.want == "" {
+			// Values JSON cannot encode are written with fmt
+			if !strings.HasPrefix(got, "0x") {
+				t.Errorf("appendLogfmtValue(%T) = %s, want the fmt form", tt.value, got)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("appendLogfmtValue(%#v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLogfmtKey(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"user", "user"},
+		{"", "_"},
+		{"a b", "a_b"},
+		{"a=b", "a_b"},
+		{`"quoted"`, "_quoted_"},
+		{"tab\tkey", "tab_key"},
+		{"ключ", "ключ"},
+	}
+	for _, tt := range tests {
+		if got := logfmtKey(tt.key); got != tt.want {
+			t.Errorf("logfmtKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
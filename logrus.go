@@ -1,36 +1,253 @@
 package abslog
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
 	stackdriver "github.com/TV4/logrus-stackdriver-formatter"
 	"github.com/sirupsen/logrus"
 )
 
+// abslogPackage is the import path of this package.
+const abslogPackage = "github.com/rendis/abslog/v3"
+
 // getLogrusLogger creates and configures a Logrus logger with the specified log level and encoder type.
 // It supports both JSON (using Stackdriver formatter) and console output formats.
 func getLogrusLogger(logLevel LogLevel, encoder EncoderType) AbsLog {
+	return buildLogrusLogger(logLevel, encoder, nil)
+}
+
+// buildLogrusLogger creates a Logrus logger writing to output, or to stderr
+// when output is nil.
+func buildLogrusLogger(logLevel LogLevel, encoder EncoderType, output Output) AbsLog {
 	logr := logrus.New()
 	logr.WithContext(context.Background())
 
 	switch encoder {
 	case JSONEncoder:
-		logr.SetFormatter(stackdriver.NewFormatter())
+		// Skip abslog and standard log frames so that error report locations point at the caller
+		formatter := stackdriver.NewFormatter(stackdriver.WithStackSkip(abslogPackage), stackdriver.WithStackSkip("log"))
+		logr.SetFormatter(&levelNameFormatter{Formatter: formatter, json: true})
 	case ConsoleEncoder:
+		logr.SetFormatter(&levelNameFormatter{Formatter: logr.Formatter})
+	case LogfmtEncoder:
+		logr.SetFormatter(&logrusEntryFormatter{encode: encodeLogfmt})
+	case GELFEncoder:
+		host, _ := os.Hostname()
+		logr.SetFormatter(&logrusEntryFormatter{encode: gelfEncoder(host)})
+	case GCPEncoder:
+		logr.SetFormatter(&logrusEntryFormatter{encode: gcpEncoder(gcpProject())})
+	case ECSEncoder:
+		logr.SetFormatter(&logrusEntryFormatter{encode: ecsEncoder(ecsServiceName())})
+	case ColorConsoleEncoder, DevelopmentEncoder:
+		// Colors are only written to a terminal, never to an output
+		color := output == nil && colorEnabled(os.Stderr)
+		logr.SetFormatter(&logrusEntryFormatter{encode: terminalEncoder(encoder, color)})
 	default:
 		panic(fmt.Sprintf("Encoder type '%v' is not supported", encoder))
 	}
 
-	logr.SetLevel(getLogrusLevel(logLevel))
+	// A custom output receives the formatted entries from the formatter
+	if output != nil {
+		logr.SetFormatter(&outputFormatter{Formatter: logr.Formatter, out: output})
+		logr.SetOutput(io.Discard)
+	}
+
+	logr.SetLevel(getLogrusLevel(minimumLevel(logLevel)))
 	logr.SetReportCaller(true)
+	// Logrus reports the first caller outside Logrus, which is inside abslog
+	logr.AddHook(callerHook{})
 
 	// Wrap in LoggerAdapter for consistent interface
-	return NewLoggerAdapter(logr)
+	return NewLoggerAdapter(&logrusLogger{logr})
+}
+
+// logrusLogger wraps a Logrus logger so that it can also receive structured fields.
+type logrusLogger struct {
+	*logrus.Logger
+}
+
+// enabled reports whether entries at the given level are written.
+func (l *logrusLogger) enabled(level LogLevel) bool {
+	return l.IsLevelEnabled(getLogrusLevel(level))
+}
+
+// logFields logs msg at the given level with fields as Logrus entry data.
+func (l *logrusLogger) logFields(level LogLevel, msg string, fields []Field, callerSkip int) {
+	data := make(logrus.Fields, len(fields))
+	for _, f := range fields {
+		data[f.Key] = f.Value
+	}
+
+	// Skip logFields, LoggerAdapter.log and the adapter method, like zap does
+	ctx := context.WithValue(context.Background(), callerCtxKey{}, callerFrame(3+callerSkip))
+	if level == TraceLevel || !isBuiltinLevel(level) {
+		// Let levelNameFormatter know the name to write
+		ctx = context.WithValue(ctx, levelCtxKey{}, level)
+	}
+	entry := l.WithFields(data).WithContext(ctx)
+
+	switch level {
+	case FatalLevel:
+		// Entry.Log does not exit the program at fatal level
+		entry.Fatal(msg)
+	default:
+		entry.Log(getLogrusLevel(level), msg)
+	}
+}
+
+// callerCtxKey is the Logrus entry context key holding the frame of the call site.
+type callerCtxKey struct{}
+
+// callerHook is a Logrus hook replacing the caller of entries with the call
+// site stored in the entry context, so that callers point outside abslog.
+type callerHook struct{}
+
+// Levels returns all the Logrus levels.
+func (callerHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire sets the caller of the entry.
+func (callerHook) Fire(e *logrus.Entry) error {
+	if e.Context == nil {
+		return nil
+	}
+	if frame, ok := e.Context.Value(callerCtxKey{}).(*runtime.Frame); ok && frame != nil {
+		e.Caller = frame
+	}
+	return nil
+}
+
+// levelCtxKey is the Logrus entry context key holding the AbsLog level of
+// entries whose name differs from the Logrus level they are written at.
+type levelCtxKey struct{}
+
+// levelNameFormatter wraps a Logrus formatter so that the trace level and
+// custom levels appear with their own name instead of the name of the Logrus
+// level they are written at.
+type levelNameFormatter struct {
+	logrus.Formatter
+	// json reports whether the wrapped formatter is the Stackdriver formatter
+	json bool
+}
+
+// stackdriverErrorKey is the key the error field is renamed to while the
+// Stackdriver formatter runs, so that it stays a field as with zap instead
+// of being appended to the message of error entries.
+const stackdriverErrorKey = "abslog\x00error"
+
+// stackdriverErrorJSONKey is stackdriverErrorKey as the formatter writes it.
+var stackdriverErrorJSONKey, _ = json.Marshal(stackdriverErrorKey)
+
+// Format formats the entry with the wrapped formatter and replaces the level name.
+func (f *levelNameFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	errValue, hasErr := e.Data[logrus.ErrorKey]
+	if f.json && hasErr {
+		delete(e.Data, logrus.ErrorKey)
+		e.Data[stackdriverErrorKey] = errValue
+		defer func() {
+			delete(e.Data, stackdriverErrorKey)
+			e.Data[logrus.ErrorKey] = errValue
+		}()
+	}
+
+	out, err := f.Formatter.Format(e)
+	if err != nil {
+		return out, err
+	}
+	if f.json && hasErr {
+		out = bytes.Replace(out, stackdriverErrorJSONKey, []byte(`"`+logrus.ErrorKey+`"`), 1)
+	}
+	if e.Context == nil {
+		return out, nil
+	}
+	level, ok := e.Context.Value(levelCtxKey{}).(LogLevel)
+	if !ok {
+		return out, nil
+	}
+	name := levelName(level)
+
+	if f.json {
+		// Cloud Logging has no trace severity, report it as debug like zap's jsonLevelEncoder
+		if level == TraceLevel {
+			name = "debug"
+		}
+		severity := []byte(`"severity":"` + strings.ToUpper(name) + `"`)
+		if old := stackdriverSeverity(e.Level); old != "" {
+			return bytes.Replace(out, []byte(`"severity":"`+old+`"`), severity, 1), nil
+		}
+		// Levels without a Stackdriver severity have no severity field
+		return bytes.Replace(out, []byte("{"), append([]byte("{"), append(severity, ',')...), 1), nil
+	}
+
+	// Plain text output: level=info
+	if old := []byte("level=" + e.Level.String()); bytes.Contains(out, old) {
+		return bytes.Replace(out, old, []byte("level="+name), 1), nil
+	}
+	// Colored output: four capital letters between color escape codes
+	old, upper := strings.ToUpper(e.Level.String()), strings.ToUpper(name)
+	return bytes.Replace(out, []byte("m"+old[:min(4, len(old))]+"\x1b[0m"), []byte("m"+upper[:min(4, len(upper))]+"\x1b[0m"), 1), nil
+}
+
+// stackdriverSeverity returns the severity written by the Stackdriver formatter for a Logrus level.
+func stackdriverSeverity(level logrus.Level) string {
+	switch level {
+	case logrus.DebugLevel:
+		return "DEBUG"
+	case logrus.InfoLevel:
+		return "INFO"
+	case logrus.WarnLevel:
+		return "WARNING"
+	case logrus.ErrorLevel:
+		return "ERROR"
+	case logrus.FatalLevel:
+		return "CRITICAL"
+	case logrus.PanicLevel:
+		return "ALERT"
+	default:
+		return ""
+	}
+}
+
+// fromLogrusEntryLevel returns the AbsLog level of a Logrus entry.
+func fromLogrusEntryLevel(e *logrus.Entry) LogLevel {
+	if e.Context != nil {
+		if level, ok := e.Context.Value(levelCtxKey{}).(LogLevel); ok {
+			return level
+		}
+	}
+	switch e.Level {
+	case logrus.TraceLevel:
+		return TraceLevel
+	case logrus.DebugLevel:
+		return DebugLevel
+	case logrus.WarnLevel:
+		return WarnLevel
+	case logrus.ErrorLevel:
+		return ErrorLevel
+	case logrus.FatalLevel:
+		return FatalLevel
+	case logrus.PanicLevel:
+		return PanicLevel
+	default:
+		return InfoLevel
+	}
 }
 
 // getLogrusLevel converts an AbsLog LogLevel to the corresponding Logrus log level.
+// Custom levels map to the Logrus level of the closest built-in level below them.
 func getLogrusLevel(logLevel LogLevel) logrus.Level {
+	logLevel = normalizeLevel(logLevel)
 	switch logLevel {
+	case TraceLevel:
+		return logrus.TraceLevel
 	case DebugLevel:
 		return logrus.DebugLevel
 	case InfoLevel:
@@ -44,6 +261,9 @@ func getLogrusLevel(logLevel LogLevel) logrus.Level {
 	case PanicLevel:
 		return logrus.PanicLevel
 	default:
+		if logLevel > TraceLevel && logLevel < PanicLevel {
+			return getLogrusLevel(baseLevel(logLevel))
+		}
 		return logrus.InfoLevel
 	}
 }
@@ -0,0 +1,134 @@
+package abslog
+
+import (
+	"expvar"
+	"sync"
+)
+
+const (
+	// metricsVarName is the name under which the log counters are published with expvar.
+	metricsVarName = "abslog"
+	// maxMetricsLoggers is the number of named loggers counted separately.
+	// Entries of further loggers are counted under otherLoggersName.
+	maxMetricsLoggers = 100
+	// otherLoggersName holds the counters of the loggers beyond maxMetricsLoggers
+	otherLoggersName = "other"
+)
+
+// MetricsRecorder receives a call for every entry logged, so that log volume
+// can be exported to a metrics system. logger is the dot-separated name of the
+// logger, empty if unnamed. Implementations must be safe for concurrent use.
+type MetricsRecorder interface {
+	RecordEntry(level LogLevel, logger string)
+}
+
+// DropRecorder may be implemented by a MetricsRecorder to also receive the
+// number of entries dropped by outputs, e.g. when the queue of the HTTP output
+// is full. output is the kind of output, e.g. "http".
+type DropRecorder interface {
+	RecordDropped(output string, count int)
+}
+
+// metrics holds the log counters published with expvar, as
+//
+//	{"abslog": {"levels": {"info": 12, ...}, "loggers": {"http": {"info": 3, ...}, ...}, "dropped": {"http": 2}}}
+var metrics = struct {
+	// levels counts entries per level name
+	levels *expvar.Map
+	// loggers holds the per-level counters of each named logger
+	loggers *expvar.Map
+	// dropped counts the entries dropped by outputs per kind of output
+	dropped *expvar.Map
+	// publish publishes the counters on the first entry logged
+	publish sync.Once
+	// mu guards the creation of per-logger counters
+	mu sync.Mutex
+	// loggerCount is the number of named loggers with their own counters
+	loggerCount int
+	// recorder is the MetricsRecorder set with SetMetricsRecorder
+	recorder MetricsRecorder
+	// recorderMu guards recorder
+	recorderMu sync.RWMutex
+}{
+	levels:  new(expvar.Map).Init(),
+	loggers: new(expvar.Map).Init(),
+	dropped: new(expvar.Map).Init(),
+}
+
+// publishMetrics publishes the log counters with expvar under name, unless
+// another variable is already published under that name. The counters are
+// still kept, and given to the MetricsRecorder, if they cannot be published.
+func publishMetrics(name string) {
+	if expvar.Get(name) != nil {
+		return
+	}
+	root := new(expvar.Map).Init()
+	root.Set("levels", metrics.levels)
+	root.Set("loggers", metrics.loggers)
+	root.Set("dropped", metrics.dropped)
+	expvar.Publish(name, root)
+}
+
+// SetMetricsRecorder sets the recorder called for every entry logged.
+// A nil recorder removes the current one. The expvar counters are always kept.
+func SetMetricsRecorder(recorder MetricsRecorder) {
+	metrics.recorderMu.Lock()
+	defer metrics.recorderMu.Unlock()
+	metrics.recorder = recorder
+}
+
+// recordEntry counts an entry logged at the given level by the named logger.
+func recordEntry(level LogLevel, logger string) {
+	metrics.publish.Do(func() { publishMetrics(metricsVarName) })
+
+	name := levelName(level)
+	metrics.levels.Add(name, 1)
+	if logger != "" {
+		loggerCounters(logger).Add(name, 1)
+	}
+
+	metrics.recorderMu.RLock()
+	recorder := metrics.recorder
+	metrics.recorderMu.RUnlock()
+	if recorder != nil {
+		recorder.RecordEntry(level, logger)
+	}
+}
+
+// recordDropped counts entries dropped by the given kind of output.
+func recordDropped(output string, count int) {
+	metrics.publish.Do(func() { publishMetrics(metricsVarName) })
+	metrics.dropped.Add(output, int64(count))
+
+	metrics.recorderMu.RLock()
+	recorder, _ := metrics.recorder.(DropRecorder)
+	metrics.recorderMu.RUnlock()
+	if recorder != nil {
+		recorder.RecordDropped(output, count)
+	}
+}
+
+// loggerCounters returns the per-level counters of the named logger, creating
+// them on first use. Once maxMetricsLoggers loggers have counters, further
+// loggers share the counters of otherLoggersName.
+func loggerCounters(logger string) *expvar.Map {
+	if m, ok := metrics.loggers.Get(logger).(*expvar.Map); ok {
+		return m
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if m, ok := metrics.loggers.Get(logger).(*expvar.Map); ok {
+		return m
+	}
+	if metrics.loggerCount >= maxMetricsLoggers {
+		logger = otherLoggersName
+		if m, ok := metrics.loggers.Get(logger).(*expvar.Map); ok {
+			return m
+		}
+	}
+	m := new(expvar.Map).Init()
+	metrics.loggers.Set(logger, m)
+	metrics.loggerCount++
+	return m
+}
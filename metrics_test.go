@@ -0,0 +1,109 @@
+package abslog
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// countingRecorder is a MetricsRecorder counting entries per level and logger.
+type countingRecorder struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (r *countingRecorder) RecordEntry(level LogLevel, logger string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[level.String()+"/"+logger]++
+}
+
+// counter returns the value of key in m, 0 if it is not set.
+func counter(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	registerTestLevels()
+
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			recorder := &countingRecorder{counts: map[string]int{}}
+			SetMetricsRecorder(recorder)
+			t.Cleanup(func() { SetMetricsRecorder(nil) })
+
+			builder, _ := newCaptureBuilder(backend.typ, JSONEncoder)
+			logger := builder.LogLevel(InfoLevel).Hooks(func(e *Entry) bool { return e.Message != "dropped" }).Build()
+			name := "metrics_" + backend.name
+
+			named := loggerCounters(name)
+			infoBefore, noticeBefore := counter(metrics.levels, "info"), counter(metrics.levels, "notice")
+			namedInfoBefore, namedNoticeBefore := counter(named, "info"), counter(named, "notice")
+			logger.Info("unnamed")
+			logger.Named(name).Info("named")
+			logger.Named(name).Log(testNoticeLevel, "custom level")
+			logger.Debug("below the logger level")
+			logger.Info("dropped")
+
+			if got := counter(metrics.levels, "info") - infoBefore; got != 2 {
+				t.Errorf("info counter increased by %d, want 2", got)
+			}
+			if got := counter(metrics.levels, "notice") - noticeBefore; got != 1 {
+				t.Errorf("notice counter increased by %d, want 1", got)
+			}
+			if counter(named, "info")-namedInfoBefore != 1 || counter(named, "notice")-namedNoticeBefore != 1 {
+				t.Errorf("logger counters = %v, want one more info and notice entry", named)
+			}
+
+			want := map[string]int{"info/": 1, "info/" + name: 1, "notice/" + name: 1}
+			if fmt.Sprint(recorder.counts) != fmt.Sprint(want) {
+				t.Errorf("recorded %v, want %v", recorder.counts, want)
+			}
+		})
+	}
+
+	published, ok := expvar.Get(metricsVarName).(*expvar.Map)
+	if !ok {
+		t.Fatalf("%s not published with expvar", metricsVarName)
+	}
+	if published.Get("levels") != metrics.levels || published.Get("loggers") != metrics.loggers {
+		t.Error("published variable does not hold the counters")
+	}
+}
+
+func TestPublishMetricsTaken(t *testing.T) {
+	taken, ok := expvar.Get("abslog_test_taken").(*expvar.String)
+	if !ok {
+		taken = expvar.NewString("abslog_test_taken")
+		taken.Set("in use")
+	}
+
+	// Publishing under a name already in use must not panic
+	publishMetrics("abslog_test_taken")
+	if expvar.Get("abslog_test_taken") != taken {
+		t.Error("the variable already published was replaced")
+	}
+}
+
+func TestMetricsLoggerCap(t *testing.T) {
+	for i := 0; i <= maxMetricsLoggers; i++ {
+		loggerCounters(fmt.Sprintf("cap_%d", i)).Add("info", 1)
+	}
+
+	count := 0
+	metrics.loggers.Do(func(expvar.KeyValue) { count++ })
+	if count > maxMetricsLoggers+1 {
+		t.Errorf("got counters for %d loggers, want at most %d", count, maxMetricsLoggers+1)
+	}
+	other, ok := metrics.loggers.Get(otherLoggersName).(*expvar.Map)
+	if !ok || counter(other, "info") == 0 {
+		t.Errorf("entries of loggers beyond the cap not counted under %q", otherLoggersName)
+	}
+	if loggerCounters("cap_over_the_cap") != other {
+		t.Error("a new logger beyond the cap did not get the shared counters")
+	}
+}
@@ -0,0 +1,148 @@
+package abslog
+
+import (
+	"runtime"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap/zapcore"
+)
+
+// Output is a destination for log entries, used by the built-in logger types
+// in place of the standard output and error streams (see AbsLogBuilder.Output).
+// Implementations must be safe for concurrent use.
+type Output interface {
+	// WriteEntry writes an entry. line is the entry encoded by the logger
+	// encoder, ending with a newline. It must not be retained after the call.
+	WriteEntry(entry *Entry, line []byte) error
+	// Sync flushes any buffered entries.
+	Sync() error
+	// Close flushes any buffered entries and releases the output resources.
+	Close() error
+}
+
+// ctxFieldsOutput is implemented by outputs that need context values as entry
+// fields rather than as a message prefix, whatever the encoder.
+type ctxFieldsOutput interface {
+	ctxAsFields() bool
+}
+
+// wantsCtxFields reports whether out needs context values as entry fields.
+func wantsCtxFields(out Output) bool {
+	o, ok := out.(ctxFieldsOutput)
+	return ok && o.ctxAsFields()
+}
+
+// loggerName returns the logger name recorded in fields, if any.
+func loggerName(fields []Field) string {
+	for _, f := range fields {
+		if f.Key == nameKey {
+			name, _ := f.Value.(string)
+			return name
+		}
+	}
+	return ""
+}
+
+// outputCore is a zap core writing entries to an Output.
+type outputCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	out    Output
+	fields []Field
+}
+
+// newOutputCore creates a zap core encoding entries with enc and writing them to out.
+func newOutputCore(enc zapcore.Encoder, out Output, enabler zapcore.LevelEnabler) zapcore.Core {
+	return &outputCore{LevelEnabler: enabler, enc: enc, out: out}
+}
+
+// With returns a copy of the core with fields added to every entry.
+func (c *outputCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &outputCore{
+		LevelEnabler: c.LevelEnabler,
+		enc:          enc,
+		out:          c.out,
+		fields:       append(slices.Clip(c.fields), fromZapFields(fields)...),
+	}
+}
+
+// Check adds the core to the checked entry if the entry level is enabled.
+func (c *outputCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write encodes the entry and writes it to the output.
+// Panic and fatal entries are synced before the program stops.
+func (c *outputCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	entry := &Entry{
+		Level:   fromZapLevel(ent.Level),
+		Time:    ent.Time,
+		Message: ent.Message,
+		Fields:  append(slices.Clip(c.fields), fromZapFields(fields)...),
+	}
+	entry.Logger = loggerName(entry.Fields)
+	if ent.Caller.Defined {
+		entry.Caller = &runtime.Frame{PC: ent.Caller.PC, File: ent.Caller.File, Line: ent.Caller.Line, Function: ent.Caller.Function}
+	}
+
+	err = c.out.WriteEntry(entry, buf.Bytes())
+	if entry.Level >= PanicLevel {
+		_ = c.out.Sync()
+	}
+	return err
+}
+
+// Sync flushes the output.
+func (c *outputCore) Sync() error {
+	return c.out.Sync()
+}
+
+// fromZapFields converts zap fields to fields holding their encoded values.
+func fromZapFields(fields []zapcore.Field) []Field {
+	enc := zapcore.NewMapObjectEncoder()
+	converted := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		f.AddTo(enc)
+		converted = append(converted, Field{Key: f.Key, Value: enc.Fields[f.Key]})
+	}
+	return converted
+}
+
+// outputFormatter is a Logrus formatter writing formatted entries to an Output.
+// It returns no bytes, so the Logrus logger output should discard its input.
+type outputFormatter struct {
+	logrus.Formatter
+	out Output
+}
+
+// Format formats the entry with the wrapped formatter and writes it to the output.
+// Panic and fatal entries are synced before the program stops.
+func (f *outputFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	// Convert the entry first, since formatters may remove data fields, e.g.
+	// the Stackdriver formatter moves the error field of error entries
+	entry := fromLogrusEntry(e)
+	line, err := f.Formatter.Format(e)
+	if err != nil {
+		return nil, err
+	}
+
+	err = f.out.WriteEntry(entry, line)
+	if entry.Level >= PanicLevel {
+		_ = f.out.Sync()
+	}
+	return nil, err
+}
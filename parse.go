@@ -0,0 +1,195 @@
+package abslog
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Compile-time checks that the configuration types can be used in flags and text-based configs.
+var (
+	_ flag.Value               = (*LogLevel)(nil)
+	_ encoding.TextMarshaler   = LogLevel(0)
+	_ encoding.TextUnmarshaler = (*LogLevel)(nil)
+	_ flag.Value               = (*EncoderType)(nil)
+	_ encoding.TextMarshaler   = EncoderType(0)
+	_ encoding.TextUnmarshaler = (*EncoderType)(nil)
+	_ flag.Value               = (*LoggerType)(nil)
+	_ encoding.TextMarshaler   = LoggerType(0)
+	_ encoding.TextUnmarshaler = (*LoggerType)(nil)
+)
+
+// encoderTypeNames holds the names of the encoder types.
+var encoderTypeNames = map[EncoderType]string{
+	ConsoleEncoder:      "console",
+	JSONEncoder:         "json",
+	LogfmtEncoder:       "logfmt",
+	GELFEncoder:         "gelf",
+	GCPEncoder:          "gcp",
+	ECSEncoder:          "ecs",
+	ColorConsoleEncoder: "color",
+	DevelopmentEncoder:  "development",
+}
+
+// loggerTypeNames holds the names of the logger types.
+var loggerTypeNames = map[LoggerType]string{
+	ZapLogger:    "zap",
+	LogrusLogger: "logrus",
+}
+
+// ParseLevel returns the level with the given name, compared case-insensitively.
+// It accepts the built-in level names (trace, debug, info, warn, error, panic,
+// fatal), "warning" as an alias of warn, and the names of custom levels
+// registered with RegisterLevel.
+func ParseLevel(name string) (LogLevel, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "warning" {
+		return WarnLevel, nil
+	}
+	for level, n := range builtinLevelNames {
+		if n == name {
+			return level, nil
+		}
+	}
+
+	customLevels.RLock()
+	defer customLevels.RUnlock()
+	for level, n := range customLevels.names {
+		if n == name {
+			return level, nil
+		}
+	}
+
+	return 0, fmt.Errorf("abslog: unknown log level %q (valid: trace, debug, info, warn, error, panic, fatal or a registered custom level)", name)
+}
+
+// String returns the name of the level, or "level(N)" for unknown levels.
+func (l LogLevel) String() string {
+	return levelName(l)
+}
+
+// MarshalText returns the name of the level.
+// It fails for levels that are neither built-in nor registered.
+func (l LogLevel) MarshalText() ([]byte, error) {
+	name := levelName(l)
+	if _, err := ParseLevel(name); err != nil {
+		return nil, fmt.Errorf("abslog: cannot marshal unknown log level %d", l)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText sets the level from its name (see ParseLevel).
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	return l.Set(string(text))
+}
+
+// Set sets the level from its name (see ParseLevel). It implements flag.Value.
+func (l *LogLevel) Set(name string) error {
+	level, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// ParseEncoderType returns the encoder type with the given name, compared
+// case-insensitively: console, json, logfmt, gelf, gcp, ecs, color
+// or development.
+func ParseEncoderType(name string) (EncoderType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for encoder, n := range encoderTypeNames {
+		if n == name {
+			return encoder, nil
+		}
+	}
+	return 0, fmt.Errorf("abslog: unknown encoder type %q (valid: %s)", name, typeNames(encoderTypeNames))
+}
+
+// String returns the name of the encoder type, or "encoder(N)" for unknown types.
+func (e EncoderType) String() string {
+	if name, ok := encoderTypeNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("encoder(%d)", e)
+}
+
+// MarshalText returns the name of the encoder type.
+func (e EncoderType) MarshalText() ([]byte, error) {
+	name, ok := encoderTypeNames[e]
+	if !ok {
+		return nil, fmt.Errorf("abslog: cannot marshal unknown encoder type %d", e)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText sets the encoder type from its name (see ParseEncoderType).
+func (e *EncoderType) UnmarshalText(text []byte) error {
+	return e.Set(string(text))
+}
+
+// Set sets the encoder type from its name (see ParseEncoderType). It implements flag.Value.
+func (e *EncoderType) Set(name string) error {
+	encoder, err := ParseEncoderType(name)
+	if err != nil {
+		return err
+	}
+	*e = encoder
+	return nil
+}
+
+// ParseLoggerType returns the logger type with the given name, compared
+// case-insensitively: zap or logrus.
+func ParseLoggerType(name string) (LoggerType, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for typ, n := range loggerTypeNames {
+		if n == name {
+			return typ, nil
+		}
+	}
+	return 0, fmt.Errorf("abslog: unknown logger type %q (valid: %s)", name, typeNames(loggerTypeNames))
+}
+
+// String returns the name of the logger type, or "logger(N)" for unknown types.
+func (t LoggerType) String() string {
+	if name, ok := loggerTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("logger(%d)", t)
+}
+
+// MarshalText returns the name of the logger type.
+func (t LoggerType) MarshalText() ([]byte, error) {
+	name, ok := loggerTypeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("abslog: cannot marshal unknown logger type %d", t)
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText sets the logger type from its name (see ParseLoggerType).
+func (t *LoggerType) UnmarshalText(text []byte) error {
+	return t.Set(string(text))
+}
+
+// Set sets the logger type from its name (see ParseLoggerType). It implements flag.Value.
+func (t *LoggerType) Set(name string) error {
+	typ, err := ParseLoggerType(name)
+	if err != nil {
+		return err
+	}
+	*t = typ
+	return nil
+}
+
+// typeNames returns the names of a configuration type ordered by value.
+func typeNames[T EncoderType | LoggerType](names map[T]string) string {
+	list := make([]string, 0, len(names))
+	for _, t := range slices.Sorted(maps.Keys(names)) {
+		list = append(list, names[t])
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,149 @@
+package abslog
+
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	registerTestLevels()
+
+	tests := []struct {
+		name string
+		want LogLevel
+		err  bool
+	}{
+		{"trace", TraceLevel, false},
+		{"DEBUG", DebugLevel, false},
+		{" info ", InfoLevel, false},
+		{"warn", WarnLevel, false},
+		{"Warning", WarnLevel, false},
+		{"error", ErrorLevel, false},
+		{"panic", PanicLevel, false},
+		{"fatal", FatalLevel, false},
+		{"notice", testNoticeLevel, false},
+		{"AUDIT", testAuditLevel, false},
+		{"verbose", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.name)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v, error %v", tt.name, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestParseEncoderType(t *testing.T) {
+	tests := []struct {
+		name string
+		want EncoderType
+	}{
+		{"console", ConsoleEncoder},
+		{"JSON", JSONEncoder},
+		{"logfmt", LogfmtEncoder},
+		{"gelf", GELFEncoder},
+		{"gcp", GCPEncoder},
+		{"ecs", ECSEncoder},
+		{"color", ColorConsoleEncoder},
+		{" development ", DevelopmentEncoder},
+	}
+	for _, tt := range tests {
+		got, err := ParseEncoderType(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseEncoderType(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+		if name := strings.ToLower(strings.TrimSpace(tt.name)); tt.want.String() != name {
+			t.Errorf("%v.String() = %q, want %q", tt.want, tt.want.String(), name)
+		}
+	}
+
+	_, err := ParseEncoderType("xml")
+	if err == nil || !strings.Contains(err.Error(), "console, json, logfmt") {
+		t.Errorf("ParseEncoderType(xml) error = %v, want the list of valid names", err)
+	}
+	if got := EncoderType(99).String(); got != "encoder(99)" {
+		t.Errorf("unknown encoder type name = %q", got)
+	}
+}
+
+func TestParseLoggerType(t *testing.T) {
+	for name, want := range map[string]LoggerType{"zap": ZapLogger, "Logrus": LogrusLogger} {
+		got, err := ParseLoggerType(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLoggerType(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseLoggerType("slog"); err == nil || !strings.Contains(err.Error(), "zap, logrus") {
+		t.Errorf("ParseLoggerType(slog) error = %v, want the list of valid names", err)
+	}
+	if got := LoggerType(9).String(); got != "logger(9)" {
+		t.Errorf("unknown logger type name = %q", got)
+	}
+}
+
+func TestTextMarshalling(t *testing.T) {
+	registerTestLevels()
+
+	type config struct {
+		Level   LogLevel    `json:"level"`
+		Encoder EncoderType `json:"encoder"`
+		Logger  LoggerType  `json:"logger"`
+	}
+
+	in := config{Level: testNoticeLevel, Encoder: LogfmtEncoder, Logger: LogrusLogger}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"level":"notice","encoder":"logfmt","logger":"logrus"}`; string(data) != want {
+		t.Errorf("marshalled %s, want %s", data, want)
+	}
+
+	var out config
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("unmarshalled %+v, want %+v", out, in)
+	}
+
+	invalid := []string{
+		`{"level":"loud"}`,
+		`{"encoder":"yaml"}`,
+		`{"logger":"glog"}`,
+	}
+	for _, data := range invalid {
+		if err := json.Unmarshal([]byte(data), &out); err == nil {
+			t.Errorf("unmarshalling %s did not fail", data)
+		}
+	}
+
+	for _, v := range []interface{ MarshalText() ([]byte, error) }{LogLevel(42), EncoderType(42), LoggerType(42)} {
+		if _, err := v.MarshalText(); err == nil {
+			t.Errorf("marshalling unknown value %v did not fail", v)
+		}
+	}
+}
+
+func TestFlagValues(t *testing.T) {
+	level, encoder, logger := InfoLevel, ConsoleEncoder, ZapLogger
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&level, "level", "")
+	fs.Var(&encoder, "encoder", "")
+	fs.Var(&logger, "logger", "")
+
+	if err := fs.Parse([]string{"-level", "debug", "-encoder", "ecs", "-logger", "logrus"}); err != nil {
+		t.Fatal(err)
+	}
+	if level != DebugLevel || encoder != ECSEncoder || logger != LogrusLogger {
+		t.Errorf("parsed %v %v %v, want debug ecs logrus", level, encoder, logger)
+	}
+
+	fs.SetOutput(&strings.Builder{})
+	if err := fs.Parse([]string{"-level", "loud"}); err == nil {
+		t.Error("parsing an unknown level did not fail")
+	}
+}
@@ -0,0 +1,141 @@
+package abslog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// defaultRedactionMask is the text that replaces redacted values.
+const defaultRedactionMask = "[REDACTED]"
+
+// RedactFunc receives the key and value of a context value or structured field
+// and returns the value to log. Message and list items are passed with an empty key.
+type RedactFunc func(key string, value any) any
+
+// redactor masks sensitive data in messages, context values and structured fields.
+// A nil redactor leaves every value untouched.
+type redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+	funcs    []RedactFunc
+	mask     string
+}
+
+// newRedactor creates an empty redactor using the default mask.
+func newRedactor() *redactor {
+	return &redactor{
+		keys: make(map[string]struct{}),
+		mask: defaultRedactionMask,
+	}
+}
+
+// clone returns a copy of the redactor that does not share its rules.
+func (r *redactor) clone() *redactor {
+	c := &redactor{
+		keys:     make(map[string]struct{}, len(r.keys)),
+		patterns: append([]*regexp.Regexp(nil), r.patterns...),
+		funcs:    append([]RedactFunc(nil), r.funcs...),
+		mask:     r.mask,
+	}
+	for k := range r.keys {
+		c.keys[k] = struct{}{}
+	}
+	return c
+}
+
+// empty reports whether the redactor has no rules configured.
+func (r *redactor) empty() bool {
+	return r == nil || (len(r.keys) == 0 && len(r.patterns) == 0 && len(r.funcs) == 0)
+}
+
+// message applies the value patterns and redaction functions to a log message.
+func (r *redactor) message(msg string) string {
+	if r.empty() {
+		return msg
+	}
+	for _, fn := range r.funcs {
+		msg = fmt.Sprint(fn("", msg))
+	}
+	return r.replace(msg)
+}
+
+// value returns the redacted form of a key/value pair.
+// Keys are matched case-insensitively; value patterns only mask the matched text.
+func (r *redactor) value(key string, value any) any {
+	if r.empty() {
+		return value
+	}
+	if _, ok := r.keys[strings.ToLower(key)]; ok {
+		return r.mask
+	}
+	for _, fn := range r.funcs {
+		value = fn(key, value)
+	}
+	if len(r.patterns) == 0 {
+		return value
+	}
+
+	switch v := value.(type) {
+	case string:
+		return r.replace(v)
+	case []string:
+		redacted := make([]string, len(v))
+		for i, item := range v {
+			redacted[i] = r.replace(item)
+		}
+		return redacted
+	case nil:
+		return nil
+	default:
+		// Only stringify values that actually contain sensitive data
+		s := fmt.Sprint(v)
+		if redacted := r.replace(s); redacted != s {
+			return redacted
+		}
+		return value
+	}
+}
+
+// fields returns a copy of fields with every value redacted.
+func (r *redactor) fields(fields []Field) []Field {
+	if r.empty() || len(fields) == 0 {
+		return fields
+	}
+	redacted := make([]Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = Field{Key: f.Key, Value: r.value(f.Key, f.Value)}
+	}
+	return redacted
+}
+
+// keyed returns a copy of fields with the values of the redacted keys masked.
+// It runs before error values are expanded, so that a redacted error is written
+// as the mask alone rather than with its _type, _chain and _stack fields.
+func (r *redactor) keyed(fields []Field) []Field {
+	if r == nil || len(r.keys) == 0 || len(fields) == 0 {
+		return fields
+	}
+	var redacted []Field
+	for i, f := range fields {
+		if _, ok := r.keys[strings.ToLower(f.Key)]; !ok {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]Field(nil), fields...)
+		}
+		redacted[i].Value = r.mask
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// replace masks every match of the value patterns in s.
+func (r *redactor) replace(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, r.mask)
+	}
+	return s
+}
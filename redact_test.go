@@ -0,0 +1,129 @@
+package abslog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	email := regexp.MustCompile(`[a-z]+@[a-z]+\.com`)
+	tests := []struct {
+		name      string
+		configure func(AbsLogBuilder)
+		encoder   EncoderType
+		msg       string
+		ctx       map[string]any
+		kv        []any
+		// wantMsg is the logged message, including the context values prefix
+		// followed by the separator and a space
+		wantMsg    string
+		wantFields map[string]string
+	}{
+		{
+			name:       "keys",
+			configure:  func(b AbsLogBuilder) { b.RedactKeys("Password", "token") },
+			encoder:    ConsoleEncoder,
+			msg:        "login",
+			ctx:        map[string]any{"token": "abc", "user": "ann"},
+			kv:         []any{"password", "secret", "PASSWORD", "secret", "attempt", 1},
+			wantMsg:    "[token=[REDACTED], user=ann] ->  login",
+			wantFields: map[string]string{"password": "[REDACTED]", "PASSWORD": "[REDACTED]", "attempt": "1"},
+		},
+		{
+			name:       "patterns",
+			configure:  func(b AbsLogBuilder) { b.RedactPatterns(email) },
+			encoder:    JSONEncoder,
+			msg:        "mail sent to ann@example.com",
+			ctx:        map[string]any{"from": "bob@example.com"},
+			kv:         []any{"to", []string{"ann@example.com", "team"}, "count", 2},
+			wantMsg:    "mail sent to [REDACTED]",
+			wantFields: map[string]string{"from": "[REDACTED]", "to": "[[REDACTED] team]", "count": "2"},
+		},
+		{
+			name: "func and mask",
+			configure: func(b AbsLogBuilder) {
+				b.RedactMask("***").RedactFunc(func(key string, value any) any {
+					if s, ok := value.(string); ok && strings.HasPrefix(s, "4111") {
+						return "****" + s[len(s)-4:]
+					}
+					return value
+				}).RedactKeys("cvv")
+			},
+			encoder:    JSONEncoder,
+			msg:        "paid",
+			kv:         []any{"card", "4111111111111234", "cvv", 123},
+			wantMsg:    "paid",
+			wantFields: map[string]string{"card": "****1234", "cvv": "***"},
+		},
+		{
+			name:       "no rules",
+			configure:  func(AbsLogBuilder) {},
+			encoder:    ConsoleEncoder,
+			msg:        "token abc",
+			kv:         []any{"password", "secret"},
+			wantMsg:    "token abc",
+			wantFields: map[string]string{"password": "secret"},
+		},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				builder, out := newCaptureBuilder(backend.typ, tt.encoder)
+				tt.configure(builder)
+				logger := builder.Build()
+
+				ctx := context.Background()
+				if tt.ctx != nil {
+					ctx = context.WithValue(ctx, GetCtxKey(), tt.ctx)
+				}
+				logger.With(tt.kv...).InfoCtx(ctx, tt.msg)
+
+				entry, _ := out.last(t)
+				if entry.Message != tt.wantMsg {
+					t.Errorf("message = %q, want %q", entry.Message, tt.wantMsg)
+				}
+				for key, want := range tt.wantFields {
+					assertField(t, entry, key, want)
+				}
+			})
+		}
+	}
+}
+
+func TestRedactionErrorFields(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			builder, out := newCaptureBuilder(backend.typ, ConsoleEncoder)
+			logger := builder.RedactPatterns(regexp.MustCompile(`secret-\d+`)).Build()
+			logger.ErrorErr(&testErr{msg: "bad key secret-42"}, "failed")
+
+			entry, _ := out.last(t)
+			assertField(t, entry, "error", "bad key [REDACTED]")
+		})
+	}
+}
+
+func TestRedactionErrorKey(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			builder, out := newCaptureBuilder(backend.typ, JSONEncoder)
+			logger := builder.RedactKeys("error").Build()
+			logger.ErrorErr(fmt.Errorf("bad key secret-42: %w", pkgErr{}), "failed")
+
+			entry, line := out.last(t)
+			assertField(t, entry, "error", "[REDACTED]")
+			if strings.Contains(line, "secret-42") || strings.Contains(line, "main.go:10") {
+				t.Errorf("redacted error written in %s", line)
+			}
+			for _, key := range []string{"error_type", "error_chain", "error_stack"} {
+				if _, ok := fieldText(entry, key); ok {
+					t.Errorf("field %q written for a redacted error", key)
+				}
+			}
+		})
+	}
+}
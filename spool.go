@@ -0,0 +1,438 @@
+package abslog
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default values for the spool configuration
+const (
+	defaultSpoolMaxSize       = 256 << 20
+	defaultSpoolSegmentSize   = 8 << 20
+	defaultSpoolRetryInterval = 5 * time.Second
+)
+
+const (
+	// spoolSegmentExt is the extension of segment files, named after their sequence number
+	spoolSegmentExt = ".seg"
+	// spoolPositionFile holds the sequence number and offset of the next record to replay
+	spoolPositionFile = "position"
+	// spoolRecordHeader is the size of the record header: data length and CRC-32, little-endian
+	spoolRecordHeader = 8
+)
+
+// ErrSpoolFull is returned when an entry is dropped because the spool reached its maximum size.
+var ErrSpoolFull = errors.New("abslog: spool full, entry dropped")
+
+// SpoolConfig configures a disk spool keeping the entries that a network
+// output could not deliver until they can be replayed.
+type SpoolConfig struct {
+	// Dir is the spool directory. It is created if needed and must not be
+	// shared by several outputs.
+	Dir string
+	// MaxSize is the maximum size of the spool in bytes. The oldest segments
+	// are removed to make room for new entries. If zero, 256 MiB is used.
+	MaxSize int64
+	// SegmentSize is the size above which a new segment file is started.
+	// If zero, 8 MiB is used.
+	SegmentSize int64
+	// RetryInterval is the interval between attempts to replay the spooled
+	// entries. If zero, 5 seconds is used.
+	RetryInterval time.Duration
+	// SyncInterval is the minimum interval between two syncs of the segment
+	// being written to disk. If zero, appended entries are synced before the
+	// append returns, so that they survive a system crash. A positive interval
+	// syncs the entries appended since the last sync with the first append
+	// after the interval, or on Sync and Close, risking their loss on a
+	// system crash for faster appends.
+	SyncInterval time.Duration
+}
+
+// spoolSegment is a segment file of the spool.
+type spoolSegment struct {
+	seq  uint64
+	size int64
+}
+
+// spool is a directory of append-only segment files holding length-prefixed,
+// checksummed records. Records are replayed in the order they were appended
+// and segments are removed once replayed. A record torn by a crash is detected
+// by its checksum and discarded with the rest of its segment.
+// Replay is at least once: records replayed before a crash and after the last
+// saved position are replayed again.
+type spool struct {
+	config SpoolConfig
+
+	mu sync.Mutex
+	// segments are the segment files, oldest first; the last one is written
+	segments []spoolSegment
+	// nextSeq is the sequence number of the next segment
+	nextSeq uint64
+	// size is the total size of the segments
+	size int64
+	// w is the last segment opened for appending, nil until the first append
+	w *os.File
+	// r is the first segment opened for reading, nil until the first read
+	r *os.File
+	// readOff is the offset of the next record to replay in the first segment
+	readOff int64
+	// lastSync is when the segment being written was last synced
+	lastSync time.Time
+}
+
+// openSpool opens the spool directory, resuming from the saved position.
+func openSpool(config SpoolConfig) (*spool, error) {
+	if config.Dir == "" {
+		return nil, errors.New("abslog: spool directory is required")
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultSpoolMaxSize
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = defaultSpoolSegmentSize
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultSpoolRetryInterval
+	}
+	if config.SyncInterval < 0 {
+		config.SyncInterval = 0
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("abslog: cannot create spool directory: %w", err)
+	}
+
+	s := &spool{config: config, nextSeq: 1}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load lists the existing segments, restores the read position and
+// truncates a torn record at the end of the last segment.
+func (s *spool) load() error {
+	files, err := os.ReadDir(s.config.Dir)
+	if err != nil {
+		return fmt.Errorf("abslog: cannot read spool directory: %w", err)
+	}
+	for _, f := range files {
+		name, ok := strings.CutSuffix(f.Name(), spoolSegmentExt)
+		if !ok {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return fmt.Errorf("abslog: cannot read spool segment: %w", err)
+		}
+		s.segments = append(s.segments, spoolSegment{seq: seq, size: info.Size()})
+		s.size += info.Size()
+	}
+	slices.SortFunc(s.segments, func(a, b spoolSegment) int { return cmp.Compare(a.seq, b.seq) })
+	if len(s.segments) == 0 {
+		return nil
+	}
+	s.nextSeq = s.segments[len(s.segments)-1].seq + 1
+
+	if data, err := os.ReadFile(filepath.Join(s.config.Dir, spoolPositionFile)); err == nil {
+		var seq uint64
+		var off int64
+		if _, err := fmt.Sscanf(string(data), "%d %d", &seq, &off); err == nil && seq == s.segments[0].seq && off <= s.segments[0].size {
+			s.readOff = off
+		}
+	}
+
+	last := &s.segments[len(s.segments)-1]
+	valid, err := s.validSize(*last)
+	if err != nil {
+		return err
+	}
+	if valid < last.size {
+		if err := os.Truncate(s.segmentPath(last.seq), valid); err != nil {
+			return fmt.Errorf("abslog: cannot repair spool segment: %w", err)
+		}
+		s.size -= last.size - valid
+		last.size = valid
+	}
+	return nil
+}
+
+// validSize returns the size of the leading valid records of a segment.
+func (s *spool) validSize(seg spoolSegment) (int64, error) {
+	f, err := os.Open(s.segmentPath(seg.seq))
+	if err != nil {
+		return 0, fmt.Errorf("abslog: cannot open spool segment: %w", err)
+	}
+	defer f.Close()
+
+	var off int64
+	for {
+		_, next, err := readSpoolRecord(f, off, seg.size)
+		if err != nil {
+			return off, nil
+		}
+		off = next
+	}
+}
+
+// segmentPath returns the path of a segment file.
+func (s *spool) segmentPath(seq uint64) string {
+	return filepath.Join(s.config.Dir, fmt.Sprintf("%020d%s", seq, spoolSegmentExt))
+}
+
+// empty reports whether every record has been replayed.
+func (s *spool) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments) == 0 || (len(s.segments) == 1 && s.readOff >= s.segments[0].size)
+}
+
+// append appends records to the last segment, starting a new segment when it
+// is full and removing the oldest segments when the spool exceeds its size.
+// The segment is then synced, unless it was synced less than SyncInterval ago.
+func (s *spool) append(records ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range records {
+		n := int64(len(record)) + spoolRecordHeader
+		for s.size+n > s.config.MaxSize && len(s.segments) > 1 {
+			s.removeOldest()
+		}
+		if s.size+n > s.config.MaxSize {
+			return ErrSpoolFull
+		}
+
+		if s.w == nil || s.segments[len(s.segments)-1].size+n > s.config.SegmentSize {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+
+		buf := make([]byte, spoolRecordHeader, n)
+		binary.LittleEndian.PutUint32(buf[0:4], uint32(len(record)))
+		binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(record))
+		buf = append(buf, record...)
+		written, err := s.w.Write(buf)
+		s.segments[len(s.segments)-1].size += int64(written)
+		s.size += int64(written)
+		if err != nil {
+			return fmt.Errorf("abslog: cannot write spool segment: %w", err)
+		}
+	}
+
+	if s.w != nil && time.Since(s.lastSync) >= s.config.SyncInterval {
+		if err := s.w.Sync(); err != nil {
+			return fmt.Errorf("abslog: cannot sync spool segment: %w", err)
+		}
+		s.lastSync = time.Now()
+	}
+	return nil
+}
+
+// rotate opens the segment records are appended to: the last segment after
+// a restart if it has room, or a new one.
+func (s *spool) rotate() error {
+	if s.w != nil {
+		_ = s.w.Sync()
+		_ = s.w.Close()
+		s.w = nil
+	} else if len(s.segments) > 0 && s.segments[len(s.segments)-1].size < s.config.SegmentSize {
+		f, err := os.OpenFile(s.segmentPath(s.segments[len(s.segments)-1].seq), os.O_WRONLY|os.O_APPEND, 0o640)
+		if err == nil {
+			s.w = f
+			return nil
+		}
+	}
+
+	seq := s.nextSeq
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return fmt.Errorf("abslog: cannot create spool segment: %w", err)
+	}
+	s.nextSeq++
+	s.segments = append(s.segments, spoolSegment{seq: seq})
+	s.w = f
+	s.syncDir()
+	return nil
+}
+
+// syncDir syncs the spool directory so that new segment files survive a
+// system crash. Errors are ignored, since not every platform supports it.
+func (s *spool) syncDir() {
+	if d, err := os.Open(s.config.Dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
+
+// removeOldest removes the first segment, replayed or not.
+func (s *spool) removeOldest() {
+	seg := s.segments[0]
+	if s.r != nil {
+		_ = s.r.Close()
+		s.r = nil
+	}
+	if len(s.segments) == 1 && s.w != nil {
+		_ = s.w.Close()
+		s.w = nil
+	}
+	_ = os.Remove(s.segmentPath(seg.seq))
+	s.segments = s.segments[1:]
+	s.size -= seg.size
+	s.readOff = 0
+	s.savePosition()
+}
+
+// replay calls fn with the spooled records in order, at most max at a time,
+// until every record is replayed or fn fails. Records are removed once fn
+// returns nil for them.
+func (s *spool) replay(max int, fn func(records [][]byte) error) error {
+	for {
+		s.mu.Lock()
+		records, seq, next, err := s.read(max)
+		if err != nil || len(records) == 0 {
+			s.savePosition()
+			s.mu.Unlock()
+			return err
+		}
+		s.mu.Unlock()
+
+		if err := fn(records); err != nil {
+			s.mu.Lock()
+			s.savePosition()
+			s.mu.Unlock()
+			return err
+		}
+
+		s.mu.Lock()
+		// The segment may have been removed to make room meanwhile
+		if len(s.segments) > 0 && s.segments[0].seq == seq {
+			s.readOff = next
+		}
+		s.mu.Unlock()
+	}
+}
+
+// read returns up to max records from the read position, with the segment
+// they belong to and the offset following them. Replayed and corrupted
+// segments are removed on the way.
+func (s *spool) read(max int) (records [][]byte, seq uint64, next int64, err error) {
+	for len(s.segments) > 0 {
+		seg := s.segments[0]
+		if s.readOff >= seg.size {
+			if len(s.segments) == 1 && s.w == nil {
+				// The last segment is only reused after a restart, keep it for appends
+				return nil, 0, 0, nil
+			}
+			s.removeOldest()
+			continue
+		}
+
+		if s.r == nil {
+			if s.r, err = os.Open(s.segmentPath(seg.seq)); err != nil {
+				return nil, 0, 0, fmt.Errorf("abslog: cannot open spool segment: %w", err)
+			}
+		}
+
+		next = s.readOff
+		for len(records) < max && next < seg.size {
+			record, end, err := readSpoolRecord(s.r, next, seg.size)
+			if err != nil {
+				break
+			}
+			records = append(records, record)
+			next = end
+		}
+		if len(records) > 0 {
+			return records, seg.seq, next, nil
+		}
+
+		// Corrupted record: the rest of the segment cannot be trusted
+		s.readOff = seg.size
+	}
+	return nil, 0, 0, nil
+}
+
+// readSpoolRecord reads the record at off in a segment of the given size,
+// returning it with the offset of the next record.
+func readSpoolRecord(r io.ReaderAt, off, size int64) ([]byte, int64, error) {
+	var header [spoolRecordHeader]byte
+	if _, err := r.ReadAt(header[:], off); err != nil {
+		return nil, 0, err
+	}
+	length := binary.LittleEndian.Uint32(header[0:4])
+	if int64(length) > size-off-spoolRecordHeader {
+		return nil, 0, errors.New("abslog: truncated spool record")
+	}
+	record := make([]byte, length)
+	if _, err := r.ReadAt(record, off+spoolRecordHeader); err != nil {
+		return nil, 0, err
+	}
+	if crc32.ChecksumIEEE(record) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("abslog: corrupted spool record")
+	}
+	return record, off + spoolRecordHeader + int64(length), nil
+}
+
+// savePosition saves the read position, replacing the position file atomically.
+func (s *spool) savePosition() {
+	path := filepath.Join(s.config.Dir, spoolPositionFile)
+	if len(s.segments) == 0 {
+		_ = os.Remove(path)
+		return
+	}
+	tmp := path + ".tmp"
+	data := fmt.Sprintf("%d %d\n", s.segments[0].seq, s.readOff)
+	if err := os.WriteFile(tmp, []byte(data), 0o640); err == nil {
+		_ = os.Rename(tmp, path)
+	}
+}
+
+// sync flushes the segment being written to disk.
+func (s *spool) sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.w == nil {
+		return nil
+	}
+	s.lastSync = time.Now()
+	return s.w.Sync()
+}
+
+// close closes the segment files and saves the read position, removing
+// the last segment if it is fully replayed.
+func (s *spool) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.r != nil {
+		_ = s.r.Close()
+		s.r = nil
+	}
+	if s.w != nil {
+		err = errors.Join(s.w.Sync(), s.w.Close())
+		s.w = nil
+	}
+	if len(s.segments) == 1 && s.readOff >= s.segments[0].size {
+		s.removeOldest()
+		return err
+	}
+	s.savePosition()
+	return err
+}
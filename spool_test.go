@@ -0,0 +1,272 @@
+package abslog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openTestSpool opens a spool in dir with the given segment and maximum sizes.
+func openTestSpool(t *testing.T, dir string, segmentSize, maxSize int64) *spool {
+	t.Helper()
+	s, err := openSpool(SpoolConfig{Dir: dir, SegmentSize: segmentSize, MaxSize: maxSize})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// records returns n records named prefix0, prefix1...
+func records(prefix string, n int) [][]byte {
+	r := make([][]byte, n)
+	for i := range r {
+		r[i] = []byte(fmt.Sprintf("%s%d", prefix, i))
+	}
+	return r
+}
+
+// replayAll replays the whole spool, returning the records as text.
+func replayAll(t *testing.T, s *spool, max int) []string {
+	t.Helper()
+	var got []string
+	err := s.replay(max, func(records [][]byte) error {
+		if len(records) > max {
+			t.Errorf("replayed %d records at once, want at most %d", len(records), max)
+		}
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// segmentFiles returns the names of the segment files in dir.
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestSpoolReplay(t *testing.T) {
+	tests := []struct {
+		name        string
+		segmentSize int64
+		count       int
+		max         int
+	}{
+		{"single segment", 1 << 20, 10, 3},
+		{"many segments", 40, 25, 4},
+		{"one record per segment", 1, 5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := openTestSpool(t, dir, tt.segmentSize, 1<<20)
+			if !s.empty() {
+				t.Fatal("new spool not empty")
+			}
+			want := records("r", tt.count)
+			if err := s.append(want...); err != nil {
+				t.Fatal(err)
+			}
+			if s.empty() {
+				t.Fatal("spool empty after append")
+			}
+
+			got := replayAll(t, s, tt.max)
+			if strings.Join(got, ",") != strings.Join(textRecords(want), ",") {
+				t.Errorf("replayed %v, want %v", got, textRecords(want))
+			}
+			if !s.empty() {
+				t.Error("spool not empty after replay")
+			}
+			if err := s.close(); err != nil {
+				t.Fatal(err)
+			}
+			if files := segmentFiles(t, dir); len(files) != 0 {
+				t.Errorf("segments left after replay: %v", files)
+			}
+		})
+	}
+}
+
+// textRecords returns records as text.
+func textRecords(records [][]byte) []string {
+	text := make([]string, len(records))
+	for i, r := range records {
+		text[i] = string(r)
+	}
+	return text
+}
+
+func TestSpoolReplayFailure(t *testing.T) {
+	s := openTestSpool(t, t.TempDir(), 1<<20, 1<<20)
+	defer s.close()
+	_ = s.append(records("r", 5)...)
+
+	// Records are only removed once fn accepts them
+	calls := 0
+	failure := errors.New("unavailable")
+	err := s.replay(2, func([][]byte) error {
+		calls++
+		if calls == 2 {
+			return failure
+		}
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("replay() = %v, want the failure of fn", err)
+	}
+	if got := replayAll(t, s, 10); strings.Join(got, ",") != "r2,r3,r4" {
+		t.Errorf("replayed %v after the failure, want r2,r3,r4", got)
+	}
+}
+
+func TestSpoolResume(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 30, 1<<20)
+	_ = s.append(records("r", 10)...)
+
+	// Replay part of the spool, then reopen it as a new process would
+	replayed := 0
+	_ = s.replay(3, func(records [][]byte) error {
+		if replayed >= 6 {
+			return errors.New("stop")
+		}
+		replayed += len(records)
+		return nil
+	})
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestSpool(t, dir, 30, 1<<20)
+	defer s.close()
+	_ = s.append([]byte("new"))
+	if got := replayAll(t, s, 10); strings.Join(got, ",") != "r6,r7,r8,r9,new" {
+		t.Errorf("replayed %v after reopening, want r6,r7,r8,r9,new", got)
+	}
+}
+
+func TestSpoolTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, dir, 1<<20, 1<<20)
+	_ = s.append(records("r", 3)...)
+	_ = s.close()
+
+	// Simulate a crash in the middle of an append
+	files := segmentFiles(t, dir)
+	f, err := os.OpenFile(files[len(files)-1], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{200, 0, 0, 0, 1, 2})
+	_ = f.Close()
+
+	s = openTestSpool(t, dir, 1<<20, 1<<20)
+	defer s.close()
+	_ = s.append([]byte("after"))
+	if got := replayAll(t, s, 10); strings.Join(got, ",") != "r0,r1,r2,after" {
+		t.Errorf("replayed %v, want the torn record discarded", got)
+	}
+}
+
+func TestSpoolCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	// Records of 10 bytes take 18 bytes: three per segment
+	s := openTestSpool(t, dir, 54, 1<<20)
+	_ = s.append(records("record-00", 6)...)
+	_ = s.close()
+
+	// Corrupt the data of the second record of the first segment
+	first := segmentFiles(t, dir)[0]
+	data, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[18+spoolRecordHeader] ^= 0xff
+	if err := os.WriteFile(first, data, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openTestSpool(t, dir, 54, 1<<20)
+	defer s.close()
+	got := replayAll(t, s, 10)
+	if strings.Join(got, ",") != "record-000,record-003,record-004,record-005" {
+		t.Errorf("replayed %v, want the rest of the corrupted segment skipped", got)
+	}
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	dir := t.TempDir()
+	// Records of 2 bytes take 10 bytes: two per segment, three segments at most
+	s := openTestSpool(t, dir, 20, 60)
+	defer s.close()
+
+	if err := s.append(records("a", 8)...); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayAll(t, s, 10); strings.Join(got, ",") != "a2,a3,a4,a5,a6,a7" {
+		t.Errorf("replayed %v, want the oldest segment removed", got)
+	}
+	if err := s.append(make([]byte, 100)); !errors.Is(err, ErrSpoolFull) {
+		t.Errorf("append() of a record larger than the spool = %v, want ErrSpoolFull", err)
+	}
+}
+
+func TestSpoolSyncInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval int64
+		// synced reports whether the second append syncs the segment
+		synced bool
+	}{
+		{"every append", 0, true},
+		{"interval", int64(1 << 62), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := openSpool(SpoolConfig{Dir: t.TempDir(), SyncInterval: time.Duration(tt.interval)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.close()
+
+			_ = s.append([]byte("first"))
+			first := s.lastSync
+			if first.IsZero() {
+				t.Fatal("first append not synced")
+			}
+			_ = s.append([]byte("second"))
+			if synced := s.lastSync.After(first); synced != tt.synced {
+				t.Errorf("second append synced = %v, want %v", synced, tt.synced)
+			}
+			_ = s.sync()
+			if !s.lastSync.After(first) {
+				t.Error("sync() did not sync the segment")
+			}
+		})
+	}
+}
+
+func TestOpenSpoolErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	_ = os.WriteFile(file, nil, 0o640)
+
+	for _, dir := range []string{"", filepath.Join(file, "spool")} {
+		if _, err := openSpool(SpoolConfig{Dir: dir}); err == nil {
+			t.Errorf("openSpool(%q) did not fail", dir)
+		}
+	}
+}
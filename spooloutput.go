@@ -0,0 +1,194 @@
+package abslog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// spoolOutput writes entries to an output, spooling them to disk while the
+// output fails and replaying them in order once it recovers.
+type spoolOutput struct {
+	out   Output
+	spool *spool
+	// mu makes the check of the spool and the write that follows atomic, so
+	// that no entry is written directly while another one is being spooled
+	mu sync.Mutex
+
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSpoolOutput wraps out so that entries it fails to write are kept in a
+// disk spool instead of being lost. While the spool holds entries, new
+// entries are appended to it to keep their order, and the spool is replayed
+// into out every RetryInterval until it is empty. Entries spooled by a
+// previous process are replayed too.
+//
+// out must report delivery failures from WriteEntry, as the syslog and
+// journald outputs do. The HTTP output delivers asynchronously and takes a
+// SpoolConfig in its own configuration instead.
+func NewSpoolOutput(out Output, config SpoolConfig) (Output, error) {
+	s, err := openSpool(config)
+	if err != nil {
+		return nil, err
+	}
+	o := &spoolOutput{
+		out:     out,
+		spool:   s,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go o.run()
+	return o, nil
+}
+
+// ctxAsFields reports whether the wrapped output needs context values as fields.
+func (o *spoolOutput) ctxAsFields() bool {
+	return wantsCtxFields(o.out)
+}
+
+// WriteEntry writes the entry to the wrapped output, or to the spool if the
+// spool is not empty or the output fails.
+func (o *spoolOutput) WriteEntry(entry *Entry, line []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.spool.empty() {
+		if err := o.out.WriteEntry(entry, line); err == nil {
+			return nil
+		}
+	}
+	record, err := encodeSpoolEntry(entry, line)
+	if err != nil {
+		return err
+	}
+	return o.spool.append(record)
+}
+
+// run replays the spool every retry interval until the output is closed.
+func (o *spoolOutput) run() {
+	defer close(o.stopped)
+
+	ticker := time.NewTicker(o.spool.config.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		o.replay()
+		select {
+		case <-ticker.C:
+		case <-o.stop:
+			return
+		}
+	}
+}
+
+// replay writes the spooled entries to the wrapped output until it fails.
+func (o *spoolOutput) replay() {
+	_ = o.spool.replay(1, func(records [][]byte) error {
+		entry, line, err := decodeSpoolEntry(records[0])
+		if err != nil {
+			// Entries that cannot be decoded would block the spool forever
+			return nil
+		}
+		return o.out.WriteEntry(entry, line)
+	})
+}
+
+// Sync flushes the wrapped output and the spool.
+func (o *spoolOutput) Sync() error {
+	return errors.Join(o.out.Sync(), o.spool.sync())
+}
+
+// Close stops replaying, then closes the spool and the wrapped output.
+// Entries still spooled are replayed by the next output using the same directory.
+func (o *spoolOutput) Close() error {
+	var err error
+	o.closeOnce.Do(func() {
+		close(o.stop)
+		<-o.stopped
+		err = errors.Join(o.spool.close(), o.out.Close())
+	})
+	return err
+}
+
+// spoolEntry is the spooled form of an entry and its encoded line.
+// The level is stored as a number, since custom levels have no text form.
+type spoolEntry struct {
+	Level   int          `json:"level"`
+	Time    time.Time    `json:"time"`
+	Message string       `json:"message"`
+	Logger  string       `json:"logger,omitempty"`
+	Caller  *spoolCaller `json:"caller,omitempty"`
+	Fields  []spoolField `json:"fields,omitempty"`
+	Line    []byte       `json:"line"`
+}
+
+// spoolCaller is the spooled form of a caller frame.
+type spoolCaller struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Function string `json:"function"`
+}
+
+// spoolField is the spooled form of a field.
+type spoolField struct {
+	Key   string `json:"key"`
+	Value any    `json:"value"`
+}
+
+// encodeSpoolEntry encodes an entry and its line as a spool record.
+// Field values are stored as JSON, or as text when JSON cannot encode them.
+func encodeSpoolEntry(entry *Entry, line []byte) ([]byte, error) {
+	e := spoolEntry{
+		Level:   int(entry.Level),
+		Time:    entry.Time,
+		Message: entry.Message,
+		Logger:  entry.Logger,
+		Fields:  make([]spoolField, len(entry.Fields)),
+		Line:    line,
+	}
+	if entry.Caller != nil {
+		e.Caller = &spoolCaller{File: entry.Caller.File, Line: entry.Caller.Line, Function: entry.Caller.Function}
+	}
+	for i, f := range entry.Fields {
+		e.Fields[i] = spoolField{Key: f.Key, Value: f.Value}
+	}
+
+	record, err := json.Marshal(e)
+	if err != nil {
+		for i, f := range entry.Fields {
+			e.Fields[i].Value = fmt.Sprint(f.Value)
+		}
+		if record, err = json.Marshal(e); err != nil {
+			return nil, fmt.Errorf("abslog: cannot spool entry: %w", err)
+		}
+	}
+	return record, nil
+}
+
+// decodeSpoolEntry decodes a spool record written by encodeSpoolEntry.
+func decodeSpoolEntry(record []byte) (*Entry, []byte, error) {
+	var e spoolEntry
+	if err := json.Unmarshal(record, &e); err != nil {
+		return nil, nil, err
+	}
+	entry := &Entry{
+		Level:   LogLevel(e.Level),
+		Time:    e.Time,
+		Message: e.Message,
+		Logger:  e.Logger,
+		Fields:  make([]Field, len(e.Fields)),
+	}
+	if e.Caller != nil {
+		entry.Caller = &runtime.Frame{File: e.Caller.File, Line: e.Caller.Line, Function: e.Caller.Function}
+	}
+	for i, f := range e.Fields {
+		entry.Fields[i] = Field{Key: f.Key, Value: f.Value}
+	}
+	return entry, e.Line, nil
+}
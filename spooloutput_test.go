@@ -0,0 +1,244 @@
+package abslog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyOutput is a captureOutput failing its writes while failing is set.
+type flakyOutput struct {
+	captureOutput
+	failing atomic.Bool
+}
+
+// WriteEntry records the entry unless the output is failing.
+func (o *flakyOutput) WriteEntry(entry *Entry, line []byte) error {
+	if o.failing.Load() {
+		return errors.New("unavailable")
+	}
+	return o.captureOutput.WriteEntry(entry, line)
+}
+
+// messages returns the messages written, in order.
+func (o *flakyOutput) messages() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	messages := make([]string, len(o.entries))
+	for i, entry := range o.entries {
+		messages[i] = entry.Message
+	}
+	return messages
+}
+
+// waitMessages waits until messages returns n messages or more.
+func waitMessages(t *testing.T, messages func() []string, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := messages()
+		if len(got) >= n {
+			return got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got messages %v, want %d", got, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSpoolEntryEncoding(t *testing.T) {
+	registerTestLevels()
+
+	entry := &Entry{
+		Level:   testNoticeLevel,
+		Time:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Message: "msg",
+		Logger:  "api",
+		Caller:  &runtime.Frame{File: "main.go", Line: 12, Function: "main.main"},
+		Fields:  []Field{{Key: "user", Value: "ann"}, {Key: "ch", Value: make(chan int)}},
+	}
+	record, err := encodeSpoolEntry(entry, []byte("line\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, line, err := decodeSpoolEntry(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Level != entry.Level || !got.Time.Equal(entry.Time) || got.Message != "msg" || got.Logger != "api" || string(line) != "line\n" {
+		t.Errorf("decoded %+v with line %q, want %+v", got, line, entry)
+	}
+	if got.Caller == nil || *got.Caller != *entry.Caller {
+		t.Errorf("decoded caller %v, want %v", got.Caller, entry.Caller)
+	}
+	// Values JSON cannot encode are spooled as text
+	if len(got.Fields) != 2 || got.Fields[0].Value != "ann" || !strings.HasPrefix(got.Fields[1].Value.(string), "0x") {
+		t.Errorf("decoded fields %v", got.Fields)
+	}
+
+	if _, _, err := decodeSpoolEntry([]byte("{")); err == nil {
+		t.Error("decoding an invalid record did not fail")
+	}
+}
+
+func TestSpoolOutput(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			flaky := &flakyOutput{}
+			out, err := NewSpoolOutput(flaky, SpoolConfig{Dir: t.TempDir(), RetryInterval: 10 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+
+			builder, _ := newCaptureBuilder(backend.typ, JSONEncoder)
+			logger := builder.Output(out).Build()
+			logger.Info("direct")
+
+			flaky.failing.Store(true)
+			logger.With("user", "ann").Info("spooled 1")
+			logger.Warn("spooled 2")
+			flaky.failing.Store(false)
+			// Entries logged while the spool is not empty are spooled behind it
+			logger.Info("after recovery")
+
+			got := waitMessages(t, flaky.messages, 4)
+			if want := "direct,spooled 1,spooled 2,after recovery"; strings.Join(got, ",") != want {
+				t.Errorf("got messages %v, want %s", got, want)
+			}
+
+			entry := flaky.entries[1]
+			assertField(t, entry, "user", "ann")
+			assertContains(t, flaky.lines[1], `"spooled 1"`)
+			if flaky.entries[2].Level != WarnLevel {
+				t.Errorf("replayed level = %v, want warn", flaky.entries[2].Level)
+			}
+		})
+	}
+}
+
+func TestSpoolOutputRestart(t *testing.T) {
+	dir := t.TempDir()
+	config := SpoolConfig{Dir: dir, RetryInterval: 10 * time.Millisecond}
+
+	flaky := &flakyOutput{}
+	flaky.failing.Store(true)
+	out, err := NewSpoolOutput(flaky, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		_ = out.WriteEntry(&Entry{Level: InfoLevel, Message: fmt.Sprint("m", i)}, nil)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next output using the directory replays the entries
+	next := &flakyOutput{}
+	out, err = NewSpoolOutput(next, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if got := waitMessages(t, next.messages, 3); strings.Join(got, ",") != "m0,m1,m2" {
+		t.Errorf("replayed %v, want m0,m1,m2", got)
+	}
+}
+
+func TestSpoolOutputConcurrent(t *testing.T) {
+	const writers, perWriter = 4, 50
+
+	flaky := &flakyOutput{}
+	out, err := NewSpoolOutput(flaky, SpoolConfig{Dir: t.TempDir(), RetryInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				// The output fails now and then
+				flaky.failing.Store(i%10 < 3)
+				_ = out.WriteEntry(&Entry{Level: InfoLevel, Message: fmt.Sprintf("%d-%03d", w, i)}, nil)
+			}
+		}()
+	}
+	wg.Wait()
+	flaky.failing.Store(false)
+
+	// Every entry is delivered and the entries of each writer keep their order
+	got := waitMessages(t, flaky.messages, writers*perWriter)
+	last := make(map[string]string)
+	for _, msg := range got {
+		writer, _, _ := strings.Cut(msg, "-")
+		if msg <= last[writer] {
+			t.Fatalf("%s delivered after %s", msg, last[writer])
+		}
+		last[writer] = msg
+	}
+	if len(got) != writers*perWriter {
+		t.Errorf("got %d messages, want %d", len(got), writers*perWriter)
+	}
+}
+
+func TestHTTPOutputSpool(t *testing.T) {
+	dir := t.TempDir()
+	var available atomic.Bool
+	c := startCollector(t, func(int) int {
+		if available.Load() {
+			return http.StatusOK
+		}
+		return http.StatusServiceUnavailable
+	})
+	config := HTTPOutputConfig{
+		URL:        c.URL,
+		MaxRetries: -1,
+		OnError:    func(error) {},
+		Spool:      &SpoolConfig{Dir: dir, RetryInterval: 10 * time.Millisecond},
+	}
+
+	out, err := NewHTTPOutput(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropped := droppedCount("http")
+	write := func(out Output, msg string) {
+		_ = out.WriteEntry(&Entry{Level: InfoLevel, Message: msg}, []byte(`{"message":"`+msg+`"}`))
+		_ = out.Sync()
+	}
+	write(out, "first")
+	write(out, "second")
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := droppedCount("http") - dropped; got != 0 {
+		t.Errorf("dropped %d entries, want them spooled", got)
+	}
+
+	// Spooled batches are replayed after a restart, before newer batches
+	available.Store(true)
+	out, err = NewHTTPOutput(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	write(out, "third")
+
+	got := waitMessages(t, c.messages, 3)
+	if strings.Join(got, ",") != "first,second,third" {
+		t.Errorf("collected %v, want first,second,third", got)
+	}
+}
@@ -0,0 +1,80 @@
+package abslog
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"strings"
+)
+
+// RedirectStdLog redirects the output of the standard library log package to
+// the global logger at the given level. The standard log flags and prefix are
+// cleared, since time and caller are already reported by the global logger.
+// It returns a function restoring the previous output, flags and prefix.
+func RedirectStdLog(level LogLevel) (restore func()) {
+	output, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(&stdLogWriter{level: level})
+
+	return func() {
+		log.SetOutput(output)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	}
+}
+
+// NewStdLogger returns a standard library *log.Logger writing to l at the given level.
+func NewStdLogger(l AbsLog, level LogLevel) *log.Logger {
+	return log.New(&stdLogWriter{logger: l, level: level}, "", 0)
+}
+
+// stdLogWriter receives the entries of a standard library log.Logger and logs
+// them at a fixed level. The log.Logger calls Write once per entry.
+type stdLogWriter struct {
+	// logger is the logger entries are sent to; nil means the global logger
+	logger AbsLog
+	level  LogLevel
+}
+
+// Write logs p, without its trailing newline, as a single entry.
+// Callers are reported at the call site of the standard log function.
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	msg := string(bytes.TrimSuffix(p, []byte("\n")))
+
+	l := w.logger
+	if l == nil {
+		l = globalLogger
+	}
+
+	a, ok := l.(*LoggerAdapter)
+	if !ok {
+		l.Log(w.level, msg)
+		return len(p), nil
+	}
+
+	// Log through a copy skipping the frames of the log package
+	c := *a
+	c.callerSkip = stdLogCallerSkip()
+	c.log(nil, w.level, "", []any{msg}, nil)
+	return len(p), nil
+}
+
+// stdLogCallerSkip returns the number of frames between the caller of
+// stdLogWriter.Write and the first frame outside the standard log package.
+func stdLogCallerSkip() int {
+	var pcs [16]uintptr
+	// Skip runtime.Callers, stdLogCallerSkip and stdLogWriter.Write
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for skip := 0; ; skip++ {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			return skip
+		}
+		if !more {
+			return 0
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package abslog
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	tests := []struct {
+		name  string
+		level LogLevel
+		call  func(l *log.Logger)
+		want  string
+	}{
+		{"Print", InfoLevel, func(l *log.Logger) { l.Print("hello ", 1) }, "hello 1"},
+		{"Printf", WarnLevel, func(l *log.Logger) { l.Printf("n=%d", 2) }, "n=2"},
+		{"Println", ErrorLevel, func(l *log.Logger) { l.Println("line") }, "line"},
+		{"multiline", DebugLevel, func(l *log.Logger) { l.Print("a\nb\n") }, "a\nb"},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+				tt.call(NewStdLogger(logger, tt.level))
+
+				if out.count() != 1 {
+					t.Fatalf("got %d entries, want 1", out.count())
+				}
+				entry, _ := out.last(t)
+				if entry.Level != tt.level || entry.Message != tt.want {
+					t.Errorf("got %v %q, want %v %q", entry.Level, entry.Message, tt.level, tt.want)
+				}
+				if entry.Caller == nil || filepath.Base(entry.Caller.File) != "stdlog_test.go" {
+					t.Errorf("caller = %+v, want stdlog_test.go", entry.Caller)
+				}
+			})
+		}
+	}
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	previous := GetLogger()
+	output, flags, prefix := log.Writer(), log.Flags(), log.Prefix()
+	t.Cleanup(func() {
+		SetLogger(previous)
+		log.SetOutput(output)
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+	})
+
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+			SetLogger(logger)
+
+			var original bytes.Buffer
+			log.SetOutput(&original)
+			log.SetFlags(log.Lshortfile)
+			log.SetPrefix("app: ")
+
+			restore := RedirectStdLog(WarnLevel)
+			log.Printf("redirected %d", 1)
+			restore()
+			log.Print("restored")
+
+			entry, _ := out.last(t)
+			if out.count() != 1 || entry.Level != WarnLevel || entry.Message != "redirected 1" {
+				t.Errorf("got %d entries, last %v %q, want one warn \"redirected 1\"", out.count(), entry.Level, entry.Message)
+			}
+			if entry.Caller == nil || filepath.Base(entry.Caller.File) != "stdlog_test.go" {
+				t.Errorf("caller = %+v, want stdlog_test.go", entry.Caller)
+			}
+			assertContains(t, original.String(), "app: stdlog_test.go:", "restored")
+			if log.Flags() != log.Lshortfile || log.Prefix() != "app: " {
+				t.Errorf("flags %d and prefix %q were not restored", log.Flags(), log.Prefix())
+			}
+		})
+	}
+}
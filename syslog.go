@@ -0,0 +1,345 @@
+package abslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogFacility represents the syslog facility of the messages.
+type SyslogFacility int8
+
+// Syslog facility constants, in the order defined by RFC 5424.
+const (
+	// SyslogKern is the kernel messages facility.
+	SyslogKern SyslogFacility = iota + 1
+	// SyslogUser is the user-level messages facility, used by default.
+	SyslogUser
+	// SyslogMail is the mail system facility.
+	SyslogMail
+	// SyslogDaemon is the system daemons facility.
+	SyslogDaemon
+	// SyslogAuth is the security/authorization messages facility.
+	SyslogAuth
+	// SyslogSyslog is the facility of messages generated internally by syslogd.
+	SyslogSyslog
+	// SyslogLPR is the line printer subsystem facility.
+	SyslogLPR
+	// SyslogNews is the network news subsystem facility.
+	SyslogNews
+	// SyslogUUCP is the UUCP subsystem facility.
+	SyslogUUCP
+	// SyslogCron is the clock daemon facility.
+	SyslogCron
+	// SyslogAuthPriv is the private security/authorization messages facility.
+	SyslogAuthPriv
+	// SyslogFTP is the FTP daemon facility.
+	SyslogFTP
+)
+
+// Local use syslog facility constants.
+const (
+	// SyslogLocal0 is the local use 0 facility.
+	SyslogLocal0 SyslogFacility = iota + 17
+	// SyslogLocal1 is the local use 1 facility.
+	SyslogLocal1
+	// SyslogLocal2 is the local use 2 facility.
+	SyslogLocal2
+	// SyslogLocal3 is the local use 3 facility.
+	SyslogLocal3
+	// SyslogLocal4 is the local use 4 facility.
+	SyslogLocal4
+	// SyslogLocal5 is the local use 5 facility.
+	SyslogLocal5
+	// SyslogLocal6 is the local use 6 facility.
+	SyslogLocal6
+	// SyslogLocal7 is the local use 7 facility.
+	SyslogLocal7
+)
+
+// SyslogFormat represents the syslog message format.
+type SyslogFormat int8
+
+// Syslog format constants.
+const (
+	// RFC5424 formats messages as defined by RFC 5424, used by default.
+	RFC5424 SyslogFormat = iota + 1
+	// RFC3164 formats messages in the legacy BSD syslog format.
+	RFC3164
+)
+
+// syslogTimeFormat is the RFC 5424 timestamp format, limited to microseconds.
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// syslogDialTimeout bounds the time spent connecting to the syslog server.
+const syslogDialTimeout = 5 * time.Second
+
+// syslogWriteTimeout bounds the time spent sending a message, so that a
+// server that stops reading does not block logging calls.
+const syslogWriteTimeout = time.Second
+
+// syslogMinBackoff and syslogMaxBackoff bound the delay between reconnection attempts.
+const (
+	syslogMinBackoff = 100 * time.Millisecond
+	syslogMaxBackoff = 30 * time.Second
+)
+
+// errSyslogReconnecting is returned for the entries written while the
+// connection to the syslog server is being reopened.
+var errSyslogReconnecting = errors.New("abslog: syslog connection lost, reconnecting")
+
+// localSyslogPaths are the sockets tried when no network is configured.
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogConfig configures a syslog output.
+type SyslogConfig struct {
+	// Network is "unix", "unixgram", "udp" or "tcp" (or their IPv4/IPv6 variants).
+	// If empty, the local syslog daemon socket at Address is used, or the first
+	// of /dev/log, /var/run/syslog and /var/run/log available when Address is empty.
+	Network string
+	// Address is the syslog server address, e.g. "localhost:514" or a socket path.
+	Address string
+	// Facility is the facility of the messages. If zero, SyslogUser is used.
+	Facility SyslogFacility
+	// AppName is the application name (the tag in RFC 3164).
+	// If empty, the name of the executable is used.
+	AppName string
+	// Hostname is the host name written in messages.
+	// If empty, the host name reported by the kernel is used.
+	Hostname string
+	// Format is the message format. If zero, RFC5424 is used.
+	Format SyslogFormat
+}
+
+// syslogOutput writes entries to a syslog server.
+type syslogOutput struct {
+	config SyslogConfig
+	pid    string
+	// done is closed by Close to stop reconnecting
+	done chan struct{}
+
+	mu sync.Mutex
+	// conn is nil while reconnecting
+	conn net.Conn
+	// stream reports whether conn is a stream connection, whose messages need framing
+	stream bool
+	closed bool
+}
+
+// NewSyslogOutput creates an output sending entries to syslog.
+// Each message holds the entry as encoded by the logger encoder. Messages sent
+// over stream connections (TCP, unix) are framed with octet counting for RFC
+// 5424 and terminated by a newline for RFC 3164. When a write fails, the
+// connection is reopened in the background, with exponential backoff, and
+// writes fail without blocking until it is back; wrap the output with
+// NewSpoolOutput to keep those entries. It fails if the first connection
+// cannot be established.
+func NewSyslogOutput(config SyslogConfig) (Output, error) {
+	if config.Facility == 0 {
+		config.Facility = SyslogUser
+	}
+	if config.Facility < SyslogKern || config.Facility > SyslogLocal7 || (config.Facility > SyslogFTP && config.Facility < SyslogLocal0) {
+		return nil, fmt.Errorf("abslog: invalid syslog facility %d", config.Facility)
+	}
+	if config.Format == 0 {
+		config.Format = RFC5424
+	}
+	if config.Format != RFC5424 && config.Format != RFC3164 {
+		return nil, fmt.Errorf("abslog: invalid syslog format %d", config.Format)
+	}
+	if config.AppName == "" {
+		config.AppName = filepath.Base(os.Args[0])
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+
+	o := &syslogOutput{config: config, pid: strconv.Itoa(os.Getpid()), done: make(chan struct{})}
+	conn, stream, err := o.dial()
+	if err != nil {
+		return nil, err
+	}
+	o.conn, o.stream = conn, stream
+	return o, nil
+}
+
+// dial opens a connection to the syslog server, reporting whether it is a
+// stream connection.
+func (o *syslogOutput) dial() (conn net.Conn, stream bool, err error) {
+	if o.config.Network != "" {
+		conn, err := net.DialTimeout(o.config.Network, o.config.Address, syslogDialTimeout)
+		if err != nil {
+			return nil, false, fmt.Errorf("abslog: cannot connect to syslog: %w", err)
+		}
+		return conn, !strings.HasPrefix(o.config.Network, "udp") && o.config.Network != "unixgram", nil
+	}
+
+	paths := localSyslogPaths
+	if o.config.Address != "" {
+		paths = []string{o.config.Address}
+	}
+	for _, path := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.DialTimeout(network, path, syslogDialTimeout); err == nil {
+				return conn, network == "unix", nil
+			}
+		}
+	}
+	return nil, false, errors.New("abslog: cannot connect to the local syslog daemon")
+}
+
+// WriteEntry sends the entry to syslog. When the write fails, the connection
+// is reopened in the background and entries written until it is back fail
+// with errSyslogReconnecting.
+func (o *syslogOutput) WriteEntry(entry *Entry, line []byte) error {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return errOutputClosed
+	}
+	if o.conn == nil {
+		return errSyslogReconnecting
+	}
+	_ = o.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	if _, err := o.conn.Write(o.message(entry, line)); err != nil {
+		_ = o.conn.Close()
+		o.conn = nil
+		go o.reconnect()
+		return fmt.Errorf("abslog: cannot write to syslog, reconnecting: %w", err)
+	}
+	return nil
+}
+
+// reconnect reopens the connection, retrying with exponential backoff until
+// it succeeds or the output is closed. The lock is not held while dialing.
+func (o *syslogOutput) reconnect() {
+	backoff := syslogMinBackoff
+	for {
+		conn, stream, err := o.dial()
+		if err == nil {
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			if o.closed {
+				_ = conn.Close()
+				return
+			}
+			o.conn, o.stream = conn, stream
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-o.done:
+			timer.Stop()
+			return
+		}
+		backoff = min(backoff*2, syslogMaxBackoff)
+	}
+}
+
+// message formats and frames a syslog message for the current connection.
+func (o *syslogOutput) message(entry *Entry, line []byte) []byte {
+	t := entry.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	pri := (int(o.config.Facility)-1)*8 + syslogSeverity(entry.Level)
+
+	var buf bytes.Buffer
+	switch o.config.Format {
+	case RFC3164:
+		fmt.Fprintf(&buf, "<%d>%s %s %s[%s]: ", pri, t.Format(time.Stamp), o.config.Hostname, o.config.AppName, o.pid)
+		if o.stream {
+			// Newline terminated framing: keep the message on a single line
+			line = bytes.ReplaceAll(line, []byte("\n"), []byte(" "))
+		}
+		buf.Write(line)
+		if o.stream {
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes()
+	default:
+		fmt.Fprintf(&buf, "<%d>1 %s %s %s %s - - ", pri, t.Format(syslogTimeFormat),
+			syslogHeaderField(o.config.Hostname, 255), syslogHeaderField(o.config.AppName, 48), o.pid)
+		buf.Write(line)
+		if o.stream {
+			// Octet counting framing (RFC 6587)
+			return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+		}
+		return buf.Bytes()
+	}
+}
+
+// Sync does nothing, since messages are not buffered.
+func (o *syslogOutput) Sync() error {
+	return nil
+}
+
+// Close closes the connection to the syslog server and stops reconnecting.
+// Later writes fail.
+func (o *syslogOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	close(o.done)
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
+
+// syslogSeverity returns the syslog severity of a level.
+// Custom levels use the severity of the closest built-in level below them.
+func syslogSeverity(level LogLevel) int {
+	switch baseLevel(level) {
+	case TraceLevel, DebugLevel:
+		return 7 // debug
+	case InfoLevel:
+		return 6 // informational
+	case WarnLevel:
+		return 4 // warning
+	case ErrorLevel:
+		return 3 // error
+	case PanicLevel:
+		return 2 // critical
+	case FatalLevel:
+		return 1 // alert
+	default:
+		return 6
+	}
+}
+
+// syslogHeaderField returns value as an RFC 5424 header field: printable
+// ASCII characters without spaces, at most maxLen long, "-" when empty.
+func syslogHeaderField(value string, maxLen int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(field) > maxLen {
+		field = field[:maxLen]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
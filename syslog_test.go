@@ -0,0 +1,372 @@
+package abslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syslogServer is a local syslog server recording the messages it receives.
+type syslogServer struct {
+	addr string
+	// stream servers frame messages with format
+	format SyslogFormat
+
+	mu       sync.Mutex
+	data     []byte
+	messages []string
+	conns    []net.Conn
+}
+
+// startSyslogServer starts a syslog server on a local socket of the given network.
+func startSyslogServer(t *testing.T, network string, format SyslogFormat) *syslogServer {
+	t.Helper()
+	s := &syslogServer{format: format}
+	switch network {
+	case "tcp":
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = ln.Close() })
+		s.addr = ln.Addr().String()
+		go s.accept(ln)
+	case "udp", "unixgram":
+		addr := "127.0.0.1:0"
+		if network == "unixgram" {
+			addr = filepath.Join(t.TempDir(), "log.sock")
+		}
+		conn, err := net.ListenPacket(network, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		s.addr = conn.LocalAddr().String()
+		go s.receive(conn)
+	}
+	t.Cleanup(s.closeConns)
+	return s
+}
+
+// accept reads the connections accepted by ln until it is closed.
+func (s *syslogServer) accept(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.read(conn)
+	}
+}
+
+// read records the stream data received on conn.
+func (s *syslogServer) read(conn net.Conn) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		s.mu.Lock()
+		s.data = append(s.data, buf[:n]...)
+		s.frame()
+		s.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// frame moves the complete messages of the stream data to messages.
+func (s *syslogServer) frame() {
+	for len(s.data) > 0 {
+		if s.format == RFC3164 {
+			i := bytes.IndexByte(s.data, '\n')
+			if i < 0 {
+				return
+			}
+			s.messages = append(s.messages, string(s.data[:i]))
+			s.data = s.data[i+1:]
+			continue
+		}
+		length, rest, ok := bytes.Cut(s.data, []byte(" "))
+		if !ok {
+			return
+		}
+		n, err := strconv.Atoi(string(length))
+		if err != nil || len(rest) < n {
+			return
+		}
+		s.messages = append(s.messages, string(rest[:n]))
+		s.data = rest[n:]
+	}
+}
+
+// receive records the datagrams received on conn.
+func (s *syslogServer) receive(conn net.PacketConn) {
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, string(buf[:n]))
+		s.mu.Unlock()
+	}
+}
+
+// closeConns closes the accepted connections.
+func (s *syslogServer) closeConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+// wait returns the first n messages, failing the test if they are not received within two seconds.
+func (s *syslogServer) wait(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.mu.Lock()
+		messages := append([]string(nil), s.messages...)
+		s.mu.Unlock()
+		if len(messages) >= n {
+			return messages
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %d messages, want %d: %q", len(messages), n, messages)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSyslogOutput(t *testing.T) {
+	tests := []struct {
+		network string
+		format  SyslogFormat
+		// info and warn are the prefixes of the messages of an info and a warn entry
+		info, warn string
+	}{
+		{"tcp", RFC5424, "<134>1 ", "<132>1 "},
+		{"tcp", RFC3164, "<134>", "<132>"},
+		{"udp", RFC5424, "<134>1 ", "<132>1 "},
+		{"udp", RFC3164, "<134>", "<132>"},
+		{"unixgram", RFC5424, "<134>1 ", "<132>1 "},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%s/%d", backend.name, tt.network, tt.format), func(t *testing.T) {
+				server := startSyslogServer(t, tt.network, tt.format)
+				config := SyslogConfig{
+					Network:  tt.network,
+					Address:  server.addr,
+					Facility: SyslogLocal0,
+					AppName:  "my app",
+					Hostname: "host1",
+					Format:   tt.format,
+				}
+				if tt.network == "unixgram" {
+					// The local daemon socket is found at Address
+					config.Network = ""
+				}
+				out, err := NewSyslogOutput(config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer out.Close()
+
+				builder, _ := newCaptureBuilder(backend.typ, JSONEncoder)
+				logger := builder.Output(out).Build()
+				logger.Info("first\nsecond line")
+				logger.With("user", "ann").Warn("warned")
+
+				messages := server.wait(t, 2)
+				info, warn := messages[0], messages[1]
+				if !strings.HasPrefix(info, tt.info) || !strings.HasPrefix(warn, tt.warn) {
+					t.Errorf("messages %q and %q, want the prefixes %q and %q", info, warn, tt.info, tt.warn)
+				}
+				assertContains(t, warn, "host1", `"user":"ann"`, "warned")
+				if strings.HasSuffix(info, "\n") {
+					t.Errorf("message %q ends with a newline", info)
+				}
+
+				pid := strconv.Itoa(os.Getpid())
+				if tt.format == RFC5424 {
+					// The app name is a header field, without spaces
+					assertContains(t, info, " host1 myapp "+pid+" - - {")
+				} else {
+					assertContains(t, info, " host1 my app["+pid+"]: {")
+				}
+				if tt.network == "tcp" && tt.format == RFC3164 && strings.Contains(info, "\n") {
+					t.Errorf("newline framed message %q spans lines", info)
+				}
+			})
+		}
+	}
+}
+
+func TestSyslogReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &syslogServer{addr: ln.Addr().String(), format: RFC5424}
+	go server.accept(ln)
+	t.Cleanup(server.closeConns)
+
+	o, err := NewSyslogOutput(SyslogConfig{Network: "tcp", Address: server.addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	write := func(msg string) error {
+		start := time.Now()
+		err := o.WriteEntry(&Entry{Level: InfoLevel, Time: time.Now(), Message: msg}, []byte(msg))
+		if elapsed := time.Since(start); elapsed > syslogWriteTimeout+500*time.Millisecond {
+			t.Errorf("WriteEntry(%q) blocked for %v", msg, elapsed)
+		}
+		return err
+	}
+	if err := write("first"); err != nil {
+		t.Fatal(err)
+	}
+	server.wait(t, 1)
+
+	// Stop the server; writes fail fast once the connection is found broken
+	_ = ln.Close()
+	server.closeConns()
+	failed := false
+	for i := 0; i < 100 && !failed; i++ {
+		failed = write("lost") != nil
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !failed {
+		t.Fatal("writes to a closed connection did not fail")
+	}
+	if err := write("lost"); !errors.Is(err, errSyslogReconnecting) {
+		t.Errorf("WriteEntry() while reconnecting = %v, want errSyslogReconnecting", err)
+	}
+
+	// Restart the server on the same address; the output reconnects in the background
+	ln, err = net.Listen("tcp", server.addr)
+	if err != nil {
+		t.Skipf("cannot listen again on %s: %v", server.addr, err)
+	}
+	defer ln.Close()
+	go server.accept(ln)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for write("back") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the output did not reconnect")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	messages := server.wait(t, 2)
+	if last := messages[len(messages)-1]; !strings.HasSuffix(last, " back") {
+		t.Errorf("last message = %q, want the message written after reconnecting", last)
+	}
+}
+
+func TestSyslogClose(t *testing.T) {
+	server := startSyslogServer(t, "udp", RFC5424)
+	o, err := NewSyslogOutput(SyslogConfig{Network: "udp", Address: server.addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Close(); err != nil {
+		t.Errorf("second Close() = %v", err)
+	}
+	if err := o.WriteEntry(&Entry{Level: InfoLevel}, []byte("late")); !errors.Is(err, errOutputClosed) {
+		t.Errorf("WriteEntry() after Close = %v, want errOutputClosed", err)
+	}
+}
+
+func TestSyslogConfig(t *testing.T) {
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = closed.Close()
+
+	tests := []struct {
+		name   string
+		config SyslogConfig
+		err    string
+	}{
+		{"facility gap", SyslogConfig{Network: "udp", Address: "127.0.0.1:514", Facility: SyslogFTP + 1}, "invalid syslog facility"},
+		{"facility too high", SyslogConfig{Network: "udp", Address: "127.0.0.1:514", Facility: SyslogLocal7 + 1}, "invalid syslog facility"},
+		{"format", SyslogConfig{Network: "udp", Address: "127.0.0.1:514", Format: RFC3164 + 1}, "invalid syslog format"},
+		{"unreachable server", SyslogConfig{Network: "tcp", Address: closed.Addr().String()}, "cannot connect to syslog"},
+		{"missing local socket", SyslogConfig{Address: filepath.Join(t.TempDir(), "missing.sock")}, "local syslog daemon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := NewSyslogOutput(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("NewSyslogOutput() = %v, %v, want an error containing %q", out, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	registerTestLevels()
+
+	tests := []struct {
+		level LogLevel
+		want  int
+	}{
+		{TraceLevel, 7},
+		{DebugLevel, 7},
+		{InfoLevel, 6},
+		{testNoticeLevel, 6},
+		{WarnLevel, 4},
+		{ErrorLevel, 3},
+		{testAuditLevel, 3},
+		{PanicLevel, 2},
+		{FatalLevel, 1},
+	}
+	for _, tt := range tests {
+		if got := syslogSeverity(tt.level); got != tt.want {
+			t.Errorf("syslogSeverity(%v) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogHeaderField(t *testing.T) {
+	tests := []struct {
+		value  string
+		maxLen int
+		want   string
+	}{
+		{"app", 48, "app"},
+		{"my app\n", 48, "myapp"},
+		{"", 48, "-"},
+		{" \t", 48, "-"},
+		{"héllo", 48, "hllo"},
+		{"abcdef", 3, "abc"},
+	}
+	for _, tt := range tests {
+		if got := syslogHeaderField(tt.value, tt.maxLen); got != tt.want {
+			t.Errorf("syslogHeaderField(%q, %d) = %q, want %q", tt.value, tt.maxLen, got, tt.want)
+		}
+	}
+}
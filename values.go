@@ -0,0 +1,65 @@
+package abslog
+
+import (
+	"context"
+	"maps"
+)
+
+// WithValues returns a copy of ctx whose context values are the values already
+// stored in ctx merged with the given key/value pairs. New values override
+// existing values with the same key, and ctx itself is never modified, so
+// middleware and handlers can each contribute values without clobbering the
+// ones set upstream.
+//
+// kv accepts alternating keys and values as well as Field values. Context values
+// stored as []string or string are not key/value pairs and are replaced.
+//
+// Values are stored under the global context key (see GetCtxKey). Use
+// WithValuesKey for loggers built with their own key.
+func WithValues(ctx context.Context, kv ...any) context.Context {
+	return WithValuesKey(ctx, contextKey, kv...)
+}
+
+// WithValuesKey is like WithValues but stores the values under the given key,
+// e.g. the key of a logger built with ContextKey (see LoggerCtxKey).
+func WithValuesKey(ctx context.Context, key ContextKeyType, kv ...any) context.Context {
+	existing := ValuesFromKey(ctx, key)
+	fields := fieldsFromKV(kv)
+
+	merged := make(map[string]any, len(existing)+len(fields))
+	maps.Copy(merged, existing)
+	for _, f := range fields {
+		merged[f.Key] = f.Value
+	}
+
+	return context.WithValue(ctx, key, merged)
+}
+
+// ValuesFrom returns a copy of the key/value pairs stored in ctx under the
+// global context key. It returns nil if ctx holds no values or holds them as
+// []string or string.
+func ValuesFrom(ctx context.Context) map[string]any {
+	return ValuesFromKey(ctx, contextKey)
+}
+
+// ValuesFromKey is like ValuesFrom but reads the values stored under the given key.
+func ValuesFromKey(ctx context.Context, key ContextKeyType) map[string]any {
+	if ctx == nil {
+		return nil
+	}
+	values, ok := ctx.Value(key).(map[string]any)
+	if !ok {
+		return nil
+	}
+	return maps.Clone(values)
+}
+
+// LoggerCtxKey returns the key logger reads context values from: the key set
+// with ContextKey on its builder, or the global context key for loggers
+// without one and for loggers not built by abslog.
+func LoggerCtxKey(logger AbsLog) ContextKeyType {
+	if a, ok := logger.(*LoggerAdapter); ok {
+		return a.currentCtxKey()
+	}
+	return contextKey
+}
@@ -0,0 +1,153 @@
+package abslog
+
+import (
+	"context"
+	"maps"
+	"testing"
+)
+
+func TestWithValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing any
+		kv       []any
+		want     map[string]any
+	}{
+		{
+			name: "empty context",
+			kv:   []any{"user", "ann", "attempt", 2},
+			want: map[string]any{"user": "ann", "attempt": 2},
+		},
+		{
+			name:     "merge",
+			existing: map[string]any{"request_id": "r1", "user": "bob"},
+			kv:       []any{"user", "ann"},
+			want:     map[string]any{"request_id": "r1", "user": "ann"},
+		},
+		{
+			name: "fields",
+			kv:   []any{Any("user", "ann"), "ok", true},
+			want: map[string]any{"user": "ann", "ok": true},
+		},
+		{
+			name: "missing value",
+			kv:   []any{"user", "ann", "orphan"},
+			want: map[string]any{"user": "ann", badKey: "orphan"},
+		},
+		{
+			name:     "list replaced",
+			existing: []string{"r1"},
+			kv:       []any{"user", "ann"},
+			want:     map[string]any{"user": "ann"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := context.Background()
+			if tt.existing != nil {
+				parent = context.WithValue(parent, GetCtxKey(), tt.existing)
+			}
+			before := ValuesFrom(parent)
+
+			ctx := WithValues(parent, tt.kv...)
+			if got := ValuesFrom(ctx); !maps.Equal(got, tt.want) {
+				t.Errorf("ValuesFrom = %v, want %v", got, tt.want)
+			}
+			if got := ValuesFrom(parent); !maps.Equal(got, before) {
+				t.Errorf("parent values changed to %v, want %v", got, before)
+			}
+		})
+	}
+}
+
+func TestValuesFromCopy(t *testing.T) {
+	ctx := WithValues(context.Background(), "user", "ann")
+	ValuesFrom(ctx)["user"] = "bob"
+	if got := ValuesFrom(ctx)["user"]; got != "ann" {
+		t.Errorf("user = %v after changing a copy, want ann", got)
+	}
+
+	if got := ValuesFrom(nil); got != nil {
+		t.Errorf("ValuesFrom(nil) = %v, want nil", got)
+	}
+}
+
+func TestWithValuesLogged(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+
+			ctx := WithValues(context.Background(), "request_id", "r1")
+			ctx = WithValues(ctx, "user", "ann")
+			logger.InfoCtx(ctx, "handled")
+
+			entry, _ := out.last(t)
+			assertField(t, entry, "request_id", "r1")
+			assertField(t, entry, "user", "ann")
+		})
+	}
+}
+
+func TestLoggerCtxKey(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			builder, out := newCaptureBuilder(backend.typ, ConsoleEncoder)
+			logger := builder.ContextKey("mylib").ContextSeparator(" | ").Build()
+
+			key := LoggerCtxKey(logger)
+			if key != "mylib" {
+				t.Fatalf("LoggerCtxKey = %q, want mylib", key)
+			}
+			if got := LoggerCtxKey(logger.Named("child").With("k", "v")); got != key {
+				t.Errorf("LoggerCtxKey of a child = %q, want %q", got, key)
+			}
+
+			// Values stored under the global key are not read by the logger
+			logger.InfoCtx(WithValues(context.Background(), "global", 1), "msg")
+			entry, _ := out.last(t)
+			if entry.Message != "msg" {
+				t.Errorf("message = %q, want values under the global key ignored", entry.Message)
+			}
+
+			ctx := WithValuesKey(context.Background(), key, "job", "sync")
+			ctx = WithValuesKey(ctx, key, "attempt", 2)
+			logger.InfoCtx(ctx, "msg")
+			entry, _ = out.last(t)
+			if want := "[attempt=2, job=sync] |  msg"; entry.Message != want {
+				t.Errorf("message = %q, want %q", entry.Message, want)
+			}
+			if got := ValuesFrom(ctx); got != nil {
+				t.Errorf("ValuesFrom = %v, want no values under the global key", got)
+			}
+			if got := ValuesFromKey(ctx, key); len(got) != 2 {
+				t.Errorf("ValuesFromKey = %v, want 2 values", got)
+			}
+		})
+	}
+
+	if got := LoggerCtxKey(GetLogger()); got != GetCtxKey() {
+		t.Errorf("LoggerCtxKey of the global logger = %q, want %q", got, GetCtxKey())
+	}
+}
+
+func TestBuildAndSetAsGlobalCtxKey(t *testing.T) {
+	previous := GetLogger()
+	t.Cleanup(func() {
+		ResetCtxKey()
+		ResetCtxSeparator()
+		SetLogger(previous)
+	})
+
+	builder, out := newCaptureBuilder(ZapLogger, ConsoleEncoder)
+	builder.ContextKey("app").ContextSeparator(" :: ").BuildAndSetAsGlobal()
+	if got := GetCtxKey(); got != "app" {
+		t.Fatalf("GetCtxKey = %q, want app", got)
+	}
+
+	InfoCtx(WithValues(context.Background(), "user", "ann"), "msg")
+	entry, _ := out.last(t)
+	if want := "[user=ann] ::  msg"; entry.Message != want {
+		t.Errorf("message = %q, want %q", entry.Message, want)
+	}
+}
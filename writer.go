@@ -0,0 +1,100 @@
+package abslog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+)
+
+// maxWriterLineLength is the length above which a partial line received by
+// a Writer is logged without waiting for the end of the line.
+const maxWriterLineLength = 64 * 1024
+
+// errWriterClosed is returned when writing to a closed Writer.
+var errWriterClosed = errors.New("abslog: write to closed writer")
+
+// Writer returns an io.WriteCloser that logs each line written to it through
+// l at the given level. Partial lines are buffered until their newline is
+// written, or until Close, which logs any remaining partial line.
+// Trailing carriage returns are removed, and lines longer than 64 KiB are
+// split. It is safe for concurrent use.
+func Writer(l AbsLog, level LogLevel) io.WriteCloser {
+	w := &lineWriter{logger: l, level: level}
+	if a, ok := l.(*LoggerAdapter); ok {
+		// Report the caller of Write, skipping lineWriter.logLine
+		c := *a
+		c.callerSkip = 1
+		w.adapter = &c
+	}
+	return w
+}
+
+// lineWriter logs each line written to it at a fixed level.
+type lineWriter struct {
+	mu     sync.Mutex
+	logger AbsLog
+	// adapter is the logger used when l is a LoggerAdapter, for accurate callers
+	adapter *LoggerAdapter
+	level   LogLevel
+	buf     []byte
+	closed  bool
+}
+
+// Write logs every complete line in p and buffers the rest.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errWriterClosed
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf = append(w.buf, p...)
+			for len(w.buf) >= maxWriterLineLength {
+				w.logLine(w.buf[:maxWriterLineLength])
+				w.buf = w.buf[maxWriterLineLength:]
+			}
+			break
+		}
+
+		line := p[:i]
+		if len(w.buf) > 0 {
+			line = append(w.buf, line...)
+		}
+		w.logLine(line)
+		w.buf = w.buf[:0]
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close logs the remaining partial line, if any. Later writes fail.
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if len(w.buf) > 0 {
+		w.logLine(w.buf)
+		w.buf = nil
+	}
+	return nil
+}
+
+// logLine logs a single line without its trailing carriage return.
+func (w *lineWriter) logLine(line []byte) {
+	msg := string(bytes.TrimSuffix(line, []byte("\r")))
+	if w.adapter != nil {
+		w.adapter.log(nil, w.level, "", []any{msg}, nil)
+		return
+	}
+	w.logger.Log(w.level, msg)
+}
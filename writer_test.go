@@ -0,0 +1,124 @@
+package abslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	long := strings.Repeat("x", maxWriterLineLength+10)
+	tests := []struct {
+		name   string
+		writes []string
+		// before are the messages logged before Close, after those logged by Close
+		before []string
+		after  []string
+	}{
+		{"single line", []string{"hello\n"}, []string{"hello"}, nil},
+		{"several lines", []string{"a\nb\n\nc\n"}, []string{"a", "b", "", "c"}, nil},
+		{"split line", []string{"hel", "lo\nwor", "ld\n"}, []string{"hello", "world"}, nil},
+		{"carriage return", []string{"dos\r\n"}, []string{"dos"}, nil},
+		{"partial line", []string{"done\npartial"}, []string{"done"}, []string{"partial"}},
+		{"long line", []string{long}, []string{long[:maxWriterLineLength]}, []string{long[maxWriterLineLength:]}},
+	}
+
+	for _, backend := range testBackends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+				w := Writer(logger, WarnLevel)
+				for _, s := range tt.writes {
+					if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+						t.Fatalf("Write(%q) = %d, %v", s, n, err)
+					}
+				}
+				assertMessages(t, out, tt.before)
+
+				if err := w.Close(); err != nil {
+					t.Fatal(err)
+				}
+				assertMessages(t, out, append(tt.before, tt.after...))
+
+				for _, entry := range out.entries {
+					if entry.Level != WarnLevel {
+						t.Errorf("level = %v, want warn", entry.Level)
+					}
+					if entry.Caller == nil || filepath.Base(entry.Caller.File) != "writer_test.go" {
+						t.Errorf("caller = %+v, want writer_test.go", entry.Caller)
+					}
+				}
+			})
+		}
+	}
+}
+
+// assertMessages fails the test if the messages written to out differ from want.
+func assertMessages(t *testing.T, out *captureOutput, want []string) {
+	t.Helper()
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if len(out.entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(out.entries), len(want))
+	}
+	for i, entry := range out.entries {
+		if entry.Message != want[i] {
+			t.Errorf("entry %d = %.40q, want %.40q", i, entry.Message, want[i])
+		}
+	}
+}
+
+func TestWriterClosed(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+			w := Writer(logger, InfoLevel)
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Errorf("second Close() = %v", err)
+			}
+			if _, err := w.Write([]byte("late\n")); !errors.Is(err, errWriterClosed) {
+				t.Errorf("Write() after Close = %v, want errWriterClosed", err)
+			}
+			if out.count() != 0 {
+				t.Errorf("got %d entries, want none", out.count())
+			}
+		})
+	}
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			logger, out := newCaptureLogger(backend.typ, JSONEncoder)
+			w := Writer(logger, InfoLevel)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						_, _ = fmt.Fprintf(w, "writer %d line %d\n", i, j)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			if out.count() != 400 {
+				t.Fatalf("got %d entries, want 400", out.count())
+			}
+			for _, entry := range out.entries {
+				if !strings.HasPrefix(entry.Message, "writer ") || bytes.Count([]byte(entry.Message), []byte("line")) != 1 {
+					t.Errorf("interleaved line %q", entry.Message)
+				}
+			}
+		})
+	}
+}
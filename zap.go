@@ -5,6 +5,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -14,12 +15,19 @@ const logTimeFormat = "2006-01-02T15:04:05Z"
 // getZapLogger creates and configures a Zap logger with the specified log level and encoder type.
 // It sets up separate outputs for stdout (info and below) and stderr (error and above).
 func getZapLogger(logLevel LogLevel, encoder EncoderType) AbsLog {
+	return buildZapLogger(logLevel, encoder, nil)
+}
+
+// buildZapLogger creates a Zap logger writing to output, or to stdout and
+// stderr when output is nil.
+func buildZapLogger(logLevel LogLevel, encoder EncoderType, output Output) AbsLog {
+	logLevel = minimumLevel(logLevel)
 
 	// Encoder config
 	cfg := zapcore.EncoderConfig{
 		MessageKey:    "message",
 		LevelKey:      "severity",
-		EncodeLevel:   zapcore.CapitalLevelEncoder,
+		EncodeLevel:   levelEncoder,
 		TimeKey:       "timestamp",
 		EncodeTime:    customTimeEncoder,
 		CallerKey:     "caller",
@@ -32,56 +40,119 @@ func getZapLogger(logLevel LogLevel, encoder EncoderType) AbsLog {
 	case ConsoleEncoder:
 		enc = zapcore.NewConsoleEncoder(cfg)
 	case JSONEncoder:
-		enc = zapcore.NewJSONEncoder(cfg)
+		jsonCfg := cfg
+		jsonCfg.EncodeLevel = jsonLevelEncoder
+		enc = zapcore.NewJSONEncoder(jsonCfg)
+	case LogfmtEncoder:
+		enc = newZapEntryEncoder(encodeLogfmt)
+	case GELFEncoder:
+		host, _ := os.Hostname()
+		enc = newZapEntryEncoder(gelfEncoder(host))
+	case GCPEncoder:
+		enc = newZapEntryEncoder(gcpEncoder(gcpProject()))
+	case ECSEncoder:
+		enc = newZapEntryEncoder(ecsEncoder(ecsServiceName()))
+	case ColorConsoleEncoder, DevelopmentEncoder:
+		enc = newZapEntryEncoder(terminalEncoder(encoder, false))
 	default:
 		panic(fmt.Sprintf("Encoder type '%v' is not supported", encoder))
 	}
 
-	// Get ZapCore equivalent of log level
-	zapLevel := getZapLevel(logLevel)
+	// Level enablers compare AbsLog levels, since custom levels do not keep
+	// their relative order once converted to zap levels
 
 	// Stdout level enabler: route info/warn/debug to stdout
 	// Only logs at or above the specified level, but below error level
 	stdoutLevels := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level >= zapLevel && level < zap.ErrorLevel
+		l := fromZapLevel(level)
+		return l >= logLevel && l < ErrorLevel
 	})
 
 	// Stderr level enabler: route error/fatal/panic to stderr
 	// Only logs at error level and above, respecting the minimum log level
 	stderrLevels := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
-		return level >= zap.ErrorLevel && level >= zapLevel
+		l := fromZapLevel(level)
+		return l >= ErrorLevel && l >= logLevel
+	})
+
+	// Stack trace level enabler: error and above
+	stacktraceLevels := zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+		return fromZapLevel(level) >= ErrorLevel
 	})
 
 	// Write syncers
 	stdoutSyncer := zapcore.Lock(os.Stdout)
 	stderrSyncer := zapcore.Lock(os.Stderr)
 
+	// Colors are only written to the streams that are terminals
+	stdoutEnc, stderrEnc := enc, enc
+	if encoder == ColorConsoleEncoder || encoder == DevelopmentEncoder {
+		stdoutEnc = newZapEntryEncoder(terminalEncoder(encoder, colorEnabled(os.Stdout)))
+		stderrEnc = newZapEntryEncoder(terminalEncoder(encoder, colorEnabled(os.Stderr)))
+	}
+
 	// Core multi-output: combines stdout and stderr cores
 	// This allows different log levels to be routed to appropriate outputs
-	core := zapcore.NewTee(
+	var core zapcore.Core = zapcore.NewTee(
 		// Core for stdout (debug, info, warn)
 		zapcore.NewCore(
-			enc,
+			stdoutEnc,
 			stdoutSyncer,
 			stdoutLevels,
 		),
 		// Core for stderr (error, fatal, panic)
 		zapcore.NewCore(
-			enc,
+			stderrEnc,
 			stderrSyncer,
 			stderrLevels,
 		),
 	)
 
+	// A custom output receives every enabled level
+	if output != nil {
+		core = newOutputCore(enc, output, zap.LevelEnablerFunc(func(level zapcore.Level) bool {
+			return fromZapLevel(level) >= logLevel
+		}))
+	}
+
 	// Create logger with caller info and stack traces
-	// AddCallerSkip(1) skips one frame to show the actual caller, not the wrapper
-	// AddStacktrace(zap.ErrorLevel) adds stack traces for error and above
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1), zap.AddStacktrace(zap.ErrorLevel))
+	// AddCallerSkip(3) skips the adapter method, LoggerAdapter.log and zapLogger.logFields
+	// frames to show the actual caller, not the wrapper
+	// AddStacktrace(stacktraceLevels) adds stack traces for error and above
+	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(3), zap.AddStacktrace(stacktraceLevels))
 	// Use sugar logger for easier variadic argument handling
 	sugar := logger.Sugar()
 
 	// Wrap in LoggerAdapter to implement the AbsLog interface
-	return NewLoggerAdapter(sugar)
+	return NewLoggerAdapter(newZapLogger(sugar))
+}
+
+// zapLogger wraps a zap SugaredLogger so that it can also receive structured fields.
+type zapLogger struct {
+	*zap.SugaredLogger
+	core zapcore.Core
+}
+
+// newZapLogger creates a zapLogger around the given SugaredLogger.
+func newZapLogger(sugar *zap.SugaredLogger) *zapLogger {
+	return &zapLogger{
+		SugaredLogger: sugar,
+		core:          sugar.Desugar().Core(),
+	}
+}
+
+// enabled reports whether entries at the given level are written.
+func (l *zapLogger) enabled(level LogLevel) bool {
+	return l.core.Enabled(getZapLevel(level))
+}
+
+// logFields logs msg at the given level with fields as zap key/value pairs.
+func (l *zapLogger) logFields(level LogLevel, msg string, fields []Field, callerSkip int) {
+	sugar := l.SugaredLogger
+	if callerSkip > 0 {
+		sugar = sugar.WithOptions(zap.AddCallerSkip(callerSkip))
+	}
+	sugar.Logw(getZapLevel(level), msg, fieldsToKV(fields)...)
 }
 
 // customTimeEncoder formats time values using the predefined logTimeFormat.
@@ -89,9 +160,38 @@ func customTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	enc.AppendString(t.Format(logTimeFormat))
 }
 
+// zapTraceLevel is the Zap level used for TraceLevel, one below zap.DebugLevel.
+const zapTraceLevel = zap.DebugLevel - 1
+
+// levelEncoder encodes Zap levels in capitals, using the AbsLog level name
+// for the trace level and custom levels.
+func levelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if l := fromZapLevel(level); l == TraceLevel || !isBuiltinLevel(l) {
+		enc.AppendString(strings.ToUpper(levelName(l)))
+		return
+	}
+	zapcore.CapitalLevelEncoder(level, enc)
+}
+
+// jsonLevelEncoder encodes Zap levels like levelEncoder, except for the trace
+// level written as DEBUG: Cloud Logging, which reads the severity field, has no
+// trace severity. The Logrus JSON encoder writes the same severity.
+func jsonLevelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	if fromZapLevel(level) == TraceLevel {
+		enc.AppendString("DEBUG")
+		return
+	}
+	levelEncoder(level, enc)
+}
+
 // getZapLevel converts an AbsLog LogLevel to the corresponding Zap log level.
+// TraceLevel maps to the level below zap.DebugLevel and custom levels keep
+// their own value, which never collides with a Zap level.
 func getZapLevel(logLevel LogLevel) zapcore.Level {
+	logLevel = normalizeLevel(logLevel)
 	switch logLevel {
+	case TraceLevel:
+		return zapTraceLevel
 	case DebugLevel:
 		return zap.DebugLevel
 	case InfoLevel:
@@ -105,6 +205,31 @@ func getZapLevel(logLevel LogLevel) zapcore.Level {
 	case FatalLevel:
 		return zap.FatalLevel
 	default:
+		if logLevel > TraceLevel && logLevel < PanicLevel {
+			return zapcore.Level(logLevel)
+		}
 		return zap.InfoLevel
 	}
 }
+
+// fromZapLevel converts a Zap level produced by getZapLevel back to the AbsLog LogLevel.
+func fromZapLevel(level zapcore.Level) LogLevel {
+	switch level {
+	case zapTraceLevel:
+		return TraceLevel
+	case zap.DebugLevel:
+		return DebugLevel
+	case zap.InfoLevel:
+		return InfoLevel
+	case zap.WarnLevel:
+		return WarnLevel
+	case zap.ErrorLevel, zap.DPanicLevel:
+		return ErrorLevel
+	case zap.PanicLevel:
+		return PanicLevel
+	case zap.FatalLevel:
+		return FatalLevel
+	default:
+		return LogLevel(level)
+	}
+}